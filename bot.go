@@ -6,20 +6,23 @@
 package telegrambot
 
 import (
+	"context"
 	"crypto/md5"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
-	apiBaseURL  = "https://api.telegram.org/bot"
-	fileBaseURL = "https://api.telegram.org/file/bot"
+	defaultAPIBaseURL = "https://api.telegram.org"
 
 	webhookPath = "/telegram/bot/webhook"
 )
@@ -28,6 +31,12 @@ const (
 	redactedString = "<REDACTED>" // confidential info will be displayed as this
 )
 
+const (
+	minPollingErrorBackoff   = 1 * time.Second  // initial backoff on errors while polling updates
+	maxPollingErrorBackoff   = 60 * time.Second // maximum backoff on errors while polling updates
+	longPollingTimeoutMargin = 10 * time.Second // margin for the client-side timeout of long polling requests
+)
+
 // Bot struct
 type Bot struct {
 	token       string // Telegram bot API's token
@@ -37,22 +46,85 @@ type Bot struct {
 	webhookPort int    // webhook port number
 	webhookURL  string // webhook url
 
+	webhookSecretToken     string       // secret token for verifying webhook requests
+	webhookAllowedNetworks []*net.IPNet // networks allowed to send webhook requests
+	webhookTrustedProxies  []*net.IPNet // proxies whose forwarded headers are trusted
+
+	apiBaseURL  string // base url of bot API server
+	fileBaseURL string // base url for downloading files
+	localMode   bool   // whether bot API server is running in local mode or not
+
 	httpClient *http.Client // http client
 
-	quitLoop chan struct{} // quit channel of monitoring loop
+	longPollingClientOnce sync.Once
+	longPollingClient     *http.Client // http client for long polling requests
+
+	retryPolicy *RetryPolicy // retry policy for failed requests
+
+	rateLimiter   RateLimiter   // rate limiter for outgoing messages
+	rateLimitMode RateLimitMode // mode of applying rate limiter
+
+	chatMigration *ChatMigration // configuration for handling chat migrations
+
+	fileIDCache FileIDCache // cache for file ids of uploaded files
+
+	pollingMutex         sync.Mutex         // mutex for stopPolling and pollingStopRequested
+	stopPolling          context.CancelFunc // function for stopping the loop of polling updates (nil if not running)
+	pollingStopRequested bool               // whether stopping was requested before the loop of polling updates started
+
+	updateHandler    UpdateHandler     // update(webhook) handler function
+	updateDispatcher *UpdateDispatcher // dispatcher for update handler
+	offsetStore      OffsetStore       // store for the offset of polled updates
+	middlewares      []Middleware      // middlewares for update handler
+
+	autoAnswerCallbackQueries bool                              // answer callback queries automatically or not
+	callbackQueriesMutex      sync.Mutex                        // mutex for callbackQueries
+	callbackQueries           map[string]*CallbackQueryResponse // responses of callback queries being handled
+
+	filesMutex sync.Mutex            // mutex for files
+	files      map[string]cachedFile // cached info of files for downloading
 
-	updateHandler func(b *Bot, update Update, err error) // update(webhook) handler function
+	meMutex sync.Mutex // mutex for me
+	me      *User      // cached info of this bot
 
 	Verbose bool // print verbose log messages or not
 }
 
+// ClientOptions is a struct of options for NewClientWithOptions().
+type ClientOptions struct {
+	// Base url of bot API server, eg. "http://localhost:8081" for a self-hosted one.
+	// (default: "https://api.telegram.org")
+	APIBaseURL string
+
+	// Base url for downloading files. (default: same as APIBaseURL)
+	FileBaseURL string
+
+	// Set it true when the bot API server is running in local mode (with `--local` option).
+	// Then File.FilePath will be an absolute path on the server's disk, instead of a path for download url.
+	LocalMode bool
+
+	// HTTP client for sending requests. (default: an internal one with sane timeouts)
+	HTTPClient *http.Client
+}
+
 // NewClient gets a new bot API client with given token string.
 func NewClient(token string) *Bot {
-	return &Bot{
-		token:       token,
-		tokenHashed: fmt.Sprintf("%x", md5.Sum([]byte(token))),
+	return NewClientWithOptions(token, ClientOptions{})
+}
 
-		httpClient: &http.Client{
+// NewClientWithOptions gets a new bot API client with given token string and options.
+func NewClientWithOptions(token string, options ClientOptions) *Bot {
+	apiBaseURL := strings.TrimRight(options.APIBaseURL, "/")
+	if apiBaseURL == "" {
+		apiBaseURL = defaultAPIBaseURL
+	}
+	fileBaseURL := strings.TrimRight(options.FileBaseURL, "/")
+	if fileBaseURL == "" {
+		fileBaseURL = apiBaseURL
+	}
+	httpClient := options.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{
 			Transport: &http.Transport{
 				DialContext: (&net.Dialer{
 					Timeout:   10 * time.Second,
@@ -63,9 +135,18 @@ func NewClient(token string) *Bot {
 				ResponseHeaderTimeout: 10 * time.Second,
 				ExpectContinueTimeout: 1 * time.Second,
 			},
-		},
+		}
+	}
 
-		quitLoop: make(chan struct{}, 1),
+	return &Bot{
+		token:       token,
+		tokenHashed: fmt.Sprintf("%x", md5.Sum([]byte(token))),
+
+		apiBaseURL:  apiBaseURL,
+		fileBaseURL: fileBaseURL,
+		localMode:   options.LocalMode,
+
+		httpClient: httpClient,
 	}
 }
 
@@ -85,41 +166,26 @@ func GenCertAndKey(domain string, outCertFilepath string, outKeyFilepath string,
 	return nil
 }
 
-// StartWebhookServerAndWait starts a webhook server(and waits forever).
+// StartWebhookServerAndWait starts a webhook server(and waits until it stops).
 // Function SetWebhook(host, port, certFilepath) should be called priorly to setup host, port, and certification file.
 // Certification file(.pem) and a private key is needed.
 // Incoming webhooks will be received through webhookHandler function.
 //
+// Returns an error if the server could not be started, or stopped unexpectedly.
+//
 // https://core.telegram.org/bots/self-signed
-func (b *Bot) StartWebhookServerAndWait(certFilepath string, keyFilepath string, webhookHandler func(b *Bot, webhook Update, err error)) {
-	b.verbose("starting webhook server on: %s (port: %d) ...", b.getWebhookPath(), b.webhookPort)
-
-	// set update handler
-	if webhookHandler == nil {
-		b.error("given webhook handler is nil")
-		return
+func (b *Bot) StartWebhookServerAndWait(certFilepath string, keyFilepath string, webhookHandler func(b *Bot, webhook Update, err error)) error {
+	server, err := b.StartWebhookServer(certFilepath, keyFilepath, webhookHandler)
+	if err != nil {
+		return err
 	}
-	b.updateHandler = webhookHandler
 
-	// routing
-	mux := http.NewServeMux()
-	mux.HandleFunc(b.getWebhookPath(), b.handleWebhook)
-
-	// start server
-	server := &http.Server{
-		Addr:              fmt.Sprintf(":%d", b.webhookPort),
-		Handler:           mux,
-		ReadTimeout:       10 * time.Second,
-		ReadHeaderTimeout: 10 * time.Second,
-		WriteTimeout:      10 * time.Second,
-		IdleTimeout:       60 * time.Second,
-	}
-	if err := server.ListenAndServeTLS(certFilepath, keyFilepath); err != nil {
-		panic(err.Error())
-	}
+	return server.Wait()
 }
 
-// StartMonitoringUpdates retrieves updates from API server constantly.
+// StartMonitoringUpdates retrieves updates from API server constantly. (short polling)
+//
+// It sleeps for `interval` seconds after each request. Use StartLongPollingUpdates() for lower latency and fewer requests.
 //
 // If webhook is registered, it may not work properly. So make sure webhook is deleted, or not registered.
 func (b *Bot) StartMonitoringUpdates(updateOffset int, interval int, updateHandler func(b *Bot, update Update, err error)) {
@@ -131,45 +197,169 @@ func (b *Bot) StartMonitoringUpdates(updateOffset int, interval int, updateHandl
 		SetLimit(100). // default: 100
 		SetTimeout(1)  // default: 0 for testing
 
+	b.monitorUpdates(options, time.Duration(interval)*time.Second, updateHandler)
+}
+
+// StartLongPollingUpdates retrieves updates from API server constantly with long polling.
+//
+// Each request waits up to `timeout` seconds on the server side until any update arrives (eg. 50),
+// and the next request is sent immediately after the received updates are dispatched.
+//
+// If webhook is registered, it may not work properly. So make sure webhook is deleted, or not registered.
+func (b *Bot) StartLongPollingUpdates(updateOffset int, timeout int, updateHandler func(b *Bot, update Update, err error)) {
+	b.verbose("starting long polling updates (timeout seconds: %d) ...", timeout)
+
+	// https://core.telegram.org/bots/api#getupdates
+	options := OptionsGetUpdates{}.
+		SetOffset(updateOffset).
+		SetLimit(100). // default: 100
+		SetTimeout(timeout)
+
+	b.monitorUpdates(options, 0, updateHandler)
+}
+
+// Retrieve updates with given options constantly, until StopMonitoringUpdates() is called.
+//
+// It sleeps for given interval after each request, and backs off exponentially on errors.
+func (b *Bot) monitorUpdates(options OptionsGetUpdates, interval time.Duration, updateHandler UpdateHandler) {
 	// set update handler
 	if updateHandler == nil {
 		b.error("given update handler is nil")
 		return
 	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b.pollingMutex.Lock()
+	if b.stopPolling != nil {
+		b.pollingMutex.Unlock()
+
+		b.error("already monitoring updates")
+		return
+	}
+	if b.pollingStopRequested { // stopped before starting
+		b.pollingStopRequested = false
+		b.pollingMutex.Unlock()
+
+		b.verbose("stopped monitoring updates before starting")
+		return
+	}
+	b.stopPolling = cancel
+	b.pollingMutex.Unlock()
+
+	defer func() {
+		b.pollingMutex.Lock()
+		b.stopPolling = nil
+		b.pollingMutex.Unlock()
+	}()
+
 	b.updateHandler = updateHandler
 
+	// load the stored offset
+	var tracker *offsetTracker
+	if b.offsetStore != nil {
+		stored, err := b.offsetStore.Load()
+		if err != nil {
+			b.error("failed to load offset (%s)", err)
+			return
+		}
+		if options["offset"].(int) < stored {
+			options["offset"] = stored
+		}
+
+		b.verbose("loaded offset: %d", stored)
+
+		tracker = newOffsetTracker(b, b.offsetStore, options["offset"].(int))
+	}
+
 	var updates APIResponseUpdates
-loop:
-	for {
-		select {
-		case <-b.quitLoop:
-			break loop
-		default:
-			if updates = b.GetUpdates(options); updates.Ok {
-				for _, update := range updates.Result {
-					// update offset (max + 1)
-					if options["offset"].(int) <= update.UpdateID {
-						options["offset"] = update.UpdateID + 1
-					}
-
-					go b.updateHandler(b, update, nil)
+	backoff := minPollingErrorBackoff
+	for ctx.Err() == nil {
+		if updates = b.GetUpdatesContext(ctx, options); updates.Ok {
+			for _, update := range updates.Result {
+				// update offset (max + 1)
+				if options["offset"].(int) <= update.UpdateID {
+					options["offset"] = update.UpdateID + 1
+				}
+
+				b.handleMigrationUpdate(update)
+
+				var done func(handled bool)
+				if tracker != nil {
+					done = tracker.track(update.UpdateID)
+				}
+
+				if err := b.dispatchUpdate(ctx, b.updateHandler, update, nil, done, false); err != nil {
+					b.error("error while dispatching update (%s)", err)
 				}
-			} else {
-				go b.updateHandler(b, Update{}, fmt.Errorf("error while retrieving updates - %s", *updates.Description))
 			}
 
-			time.Sleep(time.Duration(interval) * time.Second)
+			// wait for the handlers, not to confirm unhandled updates with the next request
+			if tracker != nil {
+				tracker.wait(ctx)
+			}
+
+			backoff = minPollingErrorBackoff
+
+			if interval > 0 {
+				sleepContext(ctx, interval)
+			}
+		} else if ctx.Err() == nil { // not stopped
+			b.dispatchUpdate(ctx, b.updateHandler, Update{}, fmt.Errorf("error while retrieving updates - %w", updates.Err()), nil, false)
+
+			b.verbose("retrying to retrieve updates in %s", backoff)
+
+			sleepContext(ctx, backoff)
+			if backoff *= 2; backoff > maxPollingErrorBackoff {
+				backoff = maxPollingErrorBackoff
+			}
 		}
 	}
 
 	b.verbose("stopped monitoring updates")
 }
 
-// StopMonitoringUpdates stops loop of polling updates
+// StopMonitoringUpdates stops loop of polling updates.
+//
+// An in-flight (long polling) request will be canceled immediately.
+// If the loop is not started yet (eg. right after `go client.StartLongPollingUpdates(...)`), it will stop as soon as it starts.
 func (b *Bot) StopMonitoringUpdates() {
 	b.verbose("stopping monitoring updates...")
 
-	b.quitLoop <- struct{}{}
+	b.pollingMutex.Lock()
+	defer b.pollingMutex.Unlock()
+
+	if b.stopPolling != nil {
+		b.stopPolling()
+	} else {
+		b.pollingStopRequested = true
+	}
+}
+
+// Get the http client for long polling requests, which does not time out while waiting for response headers.
+// (the timeout is applied with the context of each request instead)
+func (b *Bot) getLongPollingClient() *http.Client {
+	b.longPollingClientOnce.Do(func() {
+		client := *b.httpClient
+		client.Timeout = 0
+
+		if transport, ok := b.httpClient.Transport.(*http.Transport); ok && transport.ResponseHeaderTimeout > 0 {
+			cloned := transport.Clone()
+			cloned.ResponseHeaderTimeout = 0
+
+			client.Transport = cloned
+		}
+
+		b.longPollingClient = &client
+	})
+
+	return b.longPollingClient
+}
+
+// Get full URL of given API method.
+func (b *Bot) getAPIURL(method string) string {
+	return fmt.Sprintf("%s/bot%s/%s", b.apiBaseURL, b.token, method)
 }
 
 // Get webhook path generated with hash.
@@ -186,9 +376,25 @@ func (b *Bot) getWebhookURL() string {
 func (b *Bot) redact(str string) string {
 	tokenRemoved := strings.Replace(str, b.token, redactedString, -1)
 	redacted := strings.Replace(tokenRemoved, b.tokenHashed, redactedString, -1)
+	if b.webhookSecretToken != "" {
+		redacted = strings.Replace(redacted, b.webhookSecretToken, redactedString, -1)
+	}
 	return redacted
 }
 
+// Remove confidential info from given error. (eg. urls which contain the token)
+func (b *Bot) redactError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		urlErr.URL = b.redact(urlErr.URL)
+	}
+
+	if redacted := b.redact(err.Error()); redacted != err.Error() {
+		return errors.New(redacted)
+	}
+
+	return err
+}
+
 // Print formatted log message. (only when Bot.Verbose == true)
 func (b *Bot) verbose(str string, args ...interface{}) {
 	if b.Verbose {
@@ -196,6 +402,11 @@ func (b *Bot) verbose(str string, args ...interface{}) {
 	}
 }
 
+// Print formatted log message. (always, regardless of Bot.Verbose)
+func (b *Bot) info(str string, args ...interface{}) {
+	log.Printf("> %s\n", b.redact(fmt.Sprintf(str, args...)))
+}
+
 // Print formatted error message.
 func (b *Bot) error(str string, args ...interface{}) {
 	log.Printf("* %s\n", b.redact(fmt.Sprintf(str, args...)))
@@ -0,0 +1,481 @@
+package telegrambot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	maxCallbackDataLength    = 64 // maximum length of callback data in bytes
+	callbackSignatureLength  = 8  // length of truncated HMAC signature in bytes (before encoding)
+	callbackDataSeparator    = ':'
+	callbackDataEscapePrefix = '\\'
+)
+
+// CallbackDataCodec encodes structs into compact callback data (eg. "page:3:abc"), and decodes them back.
+//
+// Exported fields of structs are encoded in their order (fields tagged with `callback:"-"` are skipped),
+// and only strings, bools, integers, and floats are supported.
+// When a secret is given, the data is signed with HMAC-SHA256, so that users cannot forge it.
+//
+// https://core.telegram.org/bots/api#inlinekeyboardbutton
+type CallbackDataCodec struct {
+	secret []byte
+}
+
+// NewCallbackDataCodec returns a new CallbackDataCodec with given secret. (nil or empty for not signing)
+func NewCallbackDataCodec(secret []byte) *CallbackDataCodec {
+	return &CallbackDataCodec{
+		secret: secret,
+	}
+}
+
+// Encode encodes given prefix and struct into callback data.
+//
+// Returns an error if the encoded data is longer than 64 bytes.
+func (c *CallbackDataCodec) Encode(prefix string, data interface{}) (encoded string, err error) {
+	if prefix == "" || strings.ContainsRune(prefix, callbackDataSeparator) || strings.ContainsRune(prefix, callbackDataEscapePrefix) {
+		return "", &CallbackDataError{Data: prefix, Reason: "prefix should not be empty, or contain separators"}
+	}
+
+	var builder strings.Builder
+	builder.WriteString(prefix)
+
+	if data != nil {
+		value := reflect.Indirect(reflect.ValueOf(data))
+		if value.Kind() != reflect.Struct {
+			return "", fmt.Errorf("given data is not a struct: %T", data)
+		}
+
+		for i := 0; i < value.NumField(); i++ {
+			if !isCallbackDataField(value.Type().Field(i)) {
+				continue
+			}
+
+			var field string
+			if field, err = encodeCallbackDataField(value.Field(i)); err != nil {
+				return "", err
+			}
+
+			builder.WriteRune(callbackDataSeparator)
+			builder.WriteString(escapeCallbackDataField(field))
+		}
+	}
+
+	encoded = builder.String()
+	if len(c.secret) > 0 {
+		encoded = encoded + string(callbackDataSeparator) + c.sign(encoded)
+	}
+
+	if len(encoded) > maxCallbackDataLength {
+		return "", &CallbackDataError{Data: encoded, Reason: fmt.Sprintf("longer than %d bytes", maxCallbackDataLength)}
+	}
+
+	return encoded, nil
+}
+
+// Decode verifies given callback data, and decodes it into the struct which `data` points to.
+//
+// Returns the prefix of the callback data.
+func (c *CallbackDataCodec) Decode(callbackData string, data interface{}) (prefix string, err error) {
+	var fields []string
+	if fields, err = c.split(callbackData); err != nil {
+		return "", err
+	}
+	prefix, fields = fields[0], fields[1:]
+
+	if data != nil {
+		value := reflect.ValueOf(data)
+		if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+			return "", fmt.Errorf("given data is not a pointer to a struct: %T", data)
+		}
+		value = value.Elem()
+
+		index := 0
+		for i := 0; i < value.NumField(); i++ {
+			if !isCallbackDataField(value.Type().Field(i)) {
+				continue
+			}
+
+			if index >= len(fields) {
+				return "", &CallbackDataError{Data: callbackData, Reason: "too few fields"}
+			}
+			if err = decodeCallbackDataField(fields[index], value.Field(i)); err != nil {
+				return "", &CallbackDataError{Data: callbackData, Reason: err.Error()}
+			}
+			index++
+		}
+		if index != len(fields) {
+			return "", &CallbackDataError{Data: callbackData, Reason: "too many fields"}
+		}
+	}
+
+	return prefix, nil
+}
+
+// Prefix returns the prefix of given callback data, after verifying its signature.
+func (c *CallbackDataCodec) Prefix(callbackData string) (prefix string, err error) {
+	var fields []string
+	if fields, err = c.split(callbackData); err != nil {
+		return "", err
+	}
+
+	return fields[0], nil
+}
+
+// Button returns a new InlineKeyboardButton with given text, and callback data encoded from given prefix and struct.
+//
+// Returns an error if the encoded data is longer than 64 bytes.
+func (c *CallbackDataCodec) Button(text, prefix string, data interface{}) (button InlineKeyboardButton, err error) {
+	var encoded string
+	if encoded, err = c.Encode(prefix, data); err != nil {
+		return InlineKeyboardButton{}, err
+	}
+
+	return InlineKeyboardButton{
+		Text:         text,
+		CallbackData: &encoded,
+	}, nil
+}
+
+// Verify signature of given callback data (if signed), and split it into unescaped fields. (the first one is the prefix)
+func (c *CallbackDataCodec) split(callbackData string) (fields []string, err error) {
+	fields = []string{}
+
+	var field strings.Builder
+	var escaped bool
+	for _, r := range callbackData {
+		switch {
+		case escaped:
+			field.WriteRune(r)
+			escaped = false
+		case r == callbackDataEscapePrefix:
+			escaped = true
+		case r == callbackDataSeparator:
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if escaped {
+		return nil, &CallbackDataError{Data: callbackData, Reason: "malformed escape sequence"}
+	}
+	fields = append(fields, field.String())
+
+	if len(c.secret) > 0 {
+		if len(fields) < 2 {
+			return nil, &CallbackDataError{Data: callbackData, Reason: "no signature"}
+		}
+
+		signature := fields[len(fields)-1]
+		payload := callbackData[:len(callbackData)-len(signature)-1]
+		if !hmac.Equal([]byte(signature), []byte(c.sign(payload))) {
+			return nil, &CallbackDataError{Data: callbackData, Reason: "invalid signature"}
+		}
+		fields = fields[:len(fields)-1]
+	}
+
+	if fields[0] == "" {
+		return nil, &CallbackDataError{Data: callbackData, Reason: "no prefix"}
+	}
+
+	return fields, nil
+}
+
+// Generate a truncated HMAC-SHA256 signature of given payload.
+func (c *CallbackDataCodec) sign(payload string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:callbackSignatureLength])
+}
+
+// Check if given struct field should be encoded.
+func isCallbackDataField(field reflect.StructField) bool {
+	return field.PkgPath == "" && field.Tag.Get("callback") != "-" // exported, and not skipped
+}
+
+// Encode given field value into string.
+func encodeCallbackDataField(value reflect.Value) (string, error) {
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		if value.Bool() {
+			return "1", nil
+		}
+		return "0", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 36), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 36), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 64), nil
+	}
+
+	return "", fmt.Errorf("not supported type of field: %s", value.Type())
+}
+
+// Decode given string into the field value.
+func decodeCallbackDataField(str string, value reflect.Value) (err error) {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(str)
+	case reflect.Bool:
+		value.SetBool(str == "1")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(str, 36, value.Type().Bits()); err == nil {
+			value.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		if u, err = strconv.ParseUint(str, 36, value.Type().Bits()); err == nil {
+			value.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(str, value.Type().Bits()); err == nil {
+			value.SetFloat(f)
+		}
+	default:
+		err = fmt.Errorf("not supported type of field: %s", value.Type())
+	}
+
+	return err
+}
+
+// Escape separators in given field.
+func escapeCallbackDataField(field string) string {
+	escape := string(callbackDataEscapePrefix)
+
+	return strings.NewReplacer(
+		escape, escape+escape,
+		string(callbackDataSeparator), escape+string(callbackDataSeparator),
+	).Replace(field)
+}
+
+// CallbackDataHandler is a function type for handling callback queries with decoded callback data.
+//
+// `data` is a value of the struct type registered with the handler.
+type CallbackDataHandler func(b *Bot, update Update, query CallbackQuery, data interface{})
+
+// CallbackDataRouter routes callback queries to the handlers registered for the prefixes of their callback data,
+// with the callback data decoded by its codec.
+type CallbackDataRouter struct {
+	codec *CallbackDataCodec
+
+	mutex  sync.RWMutex
+	routes map[string]callbackDataRoute
+}
+
+// a handler registered in CallbackDataRouter
+type callbackDataRoute struct {
+	dataType reflect.Type // nil if no data
+	handler  CallbackDataHandler
+}
+
+// NewCallbackDataRouter returns a new CallbackDataRouter with given codec.
+func NewCallbackDataRouter(codec *CallbackDataCodec) *CallbackDataRouter {
+	return &CallbackDataRouter{
+		codec:  codec,
+		routes: map[string]callbackDataRoute{},
+	}
+}
+
+// Handle registers a handler for given prefix, and the struct type of given sample data. (eg. `PageData{}`, or nil for no data)
+func (r *CallbackDataRouter) Handle(prefix string, sample interface{}, handler CallbackDataHandler) *CallbackDataRouter {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var dataType reflect.Type
+	if sample != nil {
+		dataType = reflect.Indirect(reflect.ValueOf(sample)).Type()
+	}
+
+	r.routes[prefix] = callbackDataRoute{
+		dataType: dataType,
+		handler:  handler,
+	}
+
+	return r
+}
+
+// HandleCallbackQuery routes given callback query to the handler registered for its prefix.
+//
+// It can be used as a callback query handler of UpdateRouter, eg. `router.OnCallbackQuery(callbackDataRouter.HandleCallbackQuery)`.
+func (r *CallbackDataRouter) HandleCallbackQuery(b *Bot, update Update, query CallbackQuery) {
+	r.Route(b, update)
+}
+
+// Route routes given update to the handler registered for the prefix of its callback data, and returns whether it was handled or not.
+//
+// Callback queries with invalid (or forged) callback data are not handled.
+func (r *CallbackDataRouter) Route(b *Bot, update Update) (handled bool) {
+	query := update.CallbackQuery
+	if query == nil || query.Data == nil {
+		return false
+	}
+
+	prefix, err := r.codec.Prefix(*query.Data)
+	if err != nil {
+		b.error("failed to decode callback data (%s)", err)
+		return false
+	}
+
+	r.mutex.RLock()
+	route, exists := r.routes[prefix]
+	r.mutex.RUnlock()
+
+	if !exists {
+		b.verbose("no handler for callback data with prefix: %s", prefix)
+		return false
+	}
+
+	var data interface{}
+	if route.dataType != nil {
+		value := reflect.New(route.dataType)
+		if _, err = r.codec.Decode(*query.Data, value.Interface()); err != nil {
+			b.error("failed to decode callback data (%s)", err)
+			return false
+		}
+		data = value.Elem().Interface()
+	}
+
+	route.handler(b, update, *query, data)
+
+	return true
+}
+
+// CallbackQueryResponse is a response to a callback query, which will be answered automatically
+// after its handler returns. (when enabled with Bot.SetAutoAnswerCallbackQueries)
+//
+// https://core.telegram.org/bots/api#answercallbackquery
+type CallbackQueryResponse struct {
+	mutex    sync.Mutex
+	options  OptionsAnswerCallbackQuery
+	answered bool
+}
+
+// SetText sets the text of notification (or alert) to be shown to the user.
+func (r *CallbackQueryResponse) SetText(text string) *CallbackQueryResponse {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.options.SetText(text)
+
+	return r
+}
+
+// SetShowAlert sets whether to show an alert instead of a notification.
+func (r *CallbackQueryResponse) SetShowAlert(showAlert bool) *CallbackQueryResponse {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.options.SetShowAlert(showAlert)
+
+	return r
+}
+
+// SetURL sets the url to be opened by the user's client.
+func (r *CallbackQueryResponse) SetURL(url string) *CallbackQueryResponse {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.options.SetURL(url)
+
+	return r
+}
+
+// SetCacheTime sets the maximum time in seconds that the result may be cached on the client side.
+func (r *CallbackQueryResponse) SetCacheTime(cacheTime int) *CallbackQueryResponse {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.options.SetCacheTime(cacheTime)
+
+	return r
+}
+
+// SetAutoAnswerCallbackQueries sets whether to answer callback queries automatically after their update handlers return.
+// (default: false)
+//
+// Callback queries already answered with AnswerCallbackQuery in the handlers will not be answered again.
+// Handlers can set the text, alert, or url of the automatic answers with Bot.CallbackQueryResponse.
+func (b *Bot) SetAutoAnswerCallbackQueries(enabled bool) {
+	b.autoAnswerCallbackQueries = enabled
+}
+
+// CallbackQueryResponse returns the response of given callback query, which will be answered automatically
+// after its update handler returns.
+//
+// If auto-answering is not enabled (or the callback query is not being handled), the returned response will be ignored.
+func (b *Bot) CallbackQueryResponse(callbackQueryID string) *CallbackQueryResponse {
+	b.callbackQueriesMutex.Lock()
+	defer b.callbackQueriesMutex.Unlock()
+
+	if response, exists := b.callbackQueries[callbackQueryID]; exists {
+		return response
+	}
+
+	return &CallbackQueryResponse{
+		options: OptionsAnswerCallbackQuery{},
+	}
+}
+
+// Start tracking given callback query for answering it automatically.
+func (b *Bot) trackCallbackQuery(callbackQueryID string) {
+	b.callbackQueriesMutex.Lock()
+	defer b.callbackQueriesMutex.Unlock()
+
+	if b.callbackQueries == nil {
+		b.callbackQueries = map[string]*CallbackQueryResponse{}
+	}
+	b.callbackQueries[callbackQueryID] = &CallbackQueryResponse{
+		options: OptionsAnswerCallbackQuery{},
+	}
+}
+
+// Mark given callback query as answered, so that it will not be answered automatically.
+func (b *Bot) markCallbackQueryAnswered(callbackQueryID string) {
+	b.callbackQueriesMutex.Lock()
+	response, exists := b.callbackQueries[callbackQueryID]
+	b.callbackQueriesMutex.Unlock()
+
+	if exists {
+		response.mutex.Lock()
+		response.answered = true
+		response.mutex.Unlock()
+	}
+}
+
+// Stop tracking given callback query, and answer it with its response if it was not answered yet.
+func (b *Bot) answerCallbackQueryIfNeeded(callbackQueryID string) {
+	b.callbackQueriesMutex.Lock()
+	response, exists := b.callbackQueries[callbackQueryID]
+	delete(b.callbackQueries, callbackQueryID)
+	b.callbackQueriesMutex.Unlock()
+
+	if !exists {
+		return
+	}
+
+	response.mutex.Lock()
+	answered, options := response.answered, response.options
+	response.mutex.Unlock()
+
+	if !answered {
+		b.verbose("answering callback query automatically: %s", callbackQueryID)
+
+		if result := b.AnswerCallbackQuery(callbackQueryID, options); !result.Ok {
+			b.error("failed to answer callback query automatically (%s)", result.Err())
+		}
+	}
+}
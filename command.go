@@ -0,0 +1,275 @@
+package telegrambot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Command is a bot command parsed from a message.
+//
+// https://core.telegram.org/bots#commands
+type Command struct {
+	Name     string   // name of the command without the leading slash, eg. "start"
+	Username string   // username of the bot which the command is addressed to, eg. "MyBot" for "/start@MyBot" (empty if not addressed)
+	Args     []string // arguments of the command, split by spaces (quoted ones are kept together)
+	RawArgs  string   // all arguments of the command as they are
+}
+
+// CommandHandler is a function type for handling bot commands.
+type CommandHandler func(b *Bot, update Update, command Command)
+
+// CommandRouter routes bot commands in messages to their handlers.
+//
+// Commands addressed to other bots (eg. "/start@OtherBot" in groups) are ignored.
+type CommandRouter struct {
+	mutex    sync.RWMutex
+	handlers map[string][]commandRoute
+	unknown  CommandHandler
+}
+
+// a command handler registered in CommandRouter
+type commandRoute struct {
+	filter  Filter
+	handler CommandHandler
+}
+
+// NewCommandRouter returns a new CommandRouter.
+func NewCommandRouter() *CommandRouter {
+	return &CommandRouter{
+		handlers: map[string][]commandRoute{},
+	}
+}
+
+// Handle registers a handler for given command (eg. "/start" or "start"), which will be called when all of given filters match.
+//
+// Multiple handlers with filters can be registered for a command, and the first one with matching filters will be called.
+func (r *CommandRouter) Handle(command string, handler CommandHandler, filters ...Filter) *CommandRouter {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	name := normalizeCommandName(command)
+	r.handlers[name] = append(r.handlers[name], commandRoute{
+		filter:  allFilters(filters),
+		handler: handler,
+	})
+
+	return r
+}
+
+// HandleUnknown registers a handler for commands which have no registered handlers.
+func (r *CommandRouter) HandleUnknown(handler CommandHandler) *CommandRouter {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.unknown = handler
+
+	return r
+}
+
+// HandleUpdate routes given update to the handler of its command.
+//
+// It can be used as an update handler, eg. `client.StartLongPollingUpdates(0, 50, router.HandleUpdate)`.
+func (r *CommandRouter) HandleUpdate(b *Bot, update Update, err error) {
+	if err != nil {
+		b.error("error while receiving update (%s)", err)
+		return
+	}
+
+	r.Route(b, update)
+}
+
+// Route routes given update to the handler of its command, and returns whether it was handled or not.
+func (r *CommandRouter) Route(b *Bot, update Update) (handled bool) {
+	message := update.Message
+	if message == nil {
+		message = update.ChannelPost
+	}
+	if message == nil {
+		return false
+	}
+
+	command, ok := ParseCommand(*message)
+	if !ok {
+		return false
+	}
+
+	// ignore commands addressed to other bots
+	if command.Username != "" {
+		username, err := b.getUsername(context.Background())
+		if err != nil {
+			b.error("failed to get username of the bot (%s)", err)
+			return false
+		}
+		if !strings.EqualFold(command.Username, username) {
+			b.verbose("ignoring command addressed to other bot: /%s@%s", command.Name, command.Username)
+			return false
+		}
+	}
+
+	r.mutex.RLock()
+	routes, exists := r.handlers[strings.ToLower(command.Name)]
+	unknown := r.unknown
+	r.mutex.RUnlock()
+
+	if exists {
+		for _, route := range routes {
+			if route.filter(b, update) {
+				route.handler(b, update, command)
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if unknown == nil {
+		return false
+	}
+
+	unknown(b, update, command)
+
+	return true
+}
+
+// ParseCommand parses a bot command at the beginning of given message's text (or caption).
+func ParseCommand(message Message) (command Command, ok bool) {
+	var text string
+	var entities []MessageEntity
+	if message.Text != nil {
+		text, entities = *message.Text, message.Entities
+	} else if message.Caption != nil {
+		text, entities = *message.Caption, message.CaptionEntities
+	} else {
+		return Command{}, false
+	}
+
+	for _, entity := range entities {
+		if entity.Type != MessageEntityTypeBotCommand || entity.Offset != 0 {
+			continue
+		}
+
+		// offsets and lengths of entities are in UTF-16 code units
+		encoded := utf16.Encode([]rune(text))
+		if entity.Length < 2 || entity.Length > len(encoded) {
+			return Command{}, false
+		}
+		name := string(utf16.Decode(encoded[1:entity.Length])) // without the leading slash
+		rest := string(utf16.Decode(encoded[entity.Length:]))
+
+		if index := strings.Index(name, "@"); index >= 0 {
+			command.Username = name[index+1:]
+			name = name[:index]
+		}
+		command.Name = name
+		command.RawArgs = strings.TrimSpace(rest)
+		command.Args = splitCommandArgs(command.RawArgs)
+
+		return command, true
+	}
+
+	return Command{}, false
+}
+
+// Split given arguments by spaces, keeping quoted ones together.
+//
+// Only quotes at the beginning of arguments are treated as quotes, so apostrophes in words (eg. "I'm") are kept as they are.
+// Quotes can be escaped with backslashes in quoted arguments, eg. `"say \"hello\""`.
+// Unterminated quotes are also kept as literal characters, eg. `say "hi` => ["say", "\"hi"].
+func splitCommandArgs(str string) (args []string) {
+	literals := map[int]bool{} // positions of unterminated quotes
+	for {
+		var unterminated int
+		if args, unterminated = splitQuotedArgs(str, literals); unterminated < 0 {
+			return args
+		}
+		literals[unterminated] = true
+	}
+}
+
+// Split given arguments by spaces, treating quotes at given positions as literal characters.
+//
+// Returns the position of an unterminated quote, or -1 if there is none.
+func splitQuotedArgs(str string, literals map[int]bool) (args []string, unterminated int) {
+	args = []string{}
+
+	var arg strings.Builder
+	var quote rune
+	var quoteStart int
+	var inArg, escaped bool
+	for i, r := range str {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case quote != 0:
+			if r == '\\' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case !inArg && (r == '"' || r == '\'') && !literals[i]:
+			quote, quoteStart = r, i
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, quoteStart
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, -1
+}
+
+// Normalize given command name for matching. (eg. "/Start" => "start")
+func normalizeCommandName(command string) string {
+	return strings.ToLower(strings.TrimPrefix(command, "/"))
+}
+
+// Get the info of this bot. (cached after the first successful request)
+func (b *Bot) getMe(ctx context.Context) (me *User, err error) {
+	b.meMutex.Lock()
+	defer b.meMutex.Unlock()
+
+	if b.me == nil {
+		response := b.GetMeContext(ctx)
+		if !response.Ok {
+			return nil, fmt.Errorf("failed to get info of the bot - %w", response.Err())
+		} else if response.Result == nil {
+			return nil, fmt.Errorf("failed to get info of the bot - no result")
+		}
+		b.me = response.Result
+	}
+
+	return b.me, nil
+}
+
+// Get the username of this bot.
+func (b *Bot) getUsername(ctx context.Context) (username string, err error) {
+	var me *User
+	if me, err = b.getMe(ctx); err != nil {
+		return "", err
+	}
+
+	if me.Username == nil {
+		return "", fmt.Errorf("bot has no username")
+	}
+
+	return *me.Username, nil
+}
@@ -0,0 +1,113 @@
+package telegrambot
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// Generate a message with given text and a bot command entity of given length (in UTF-16 code units) at given offset.
+func commandMessage(text string, offset, length int) Message {
+	return Message{
+		Text: &text,
+		Entities: []MessageEntity{
+			{Type: MessageEntityTypeBotCommand, Offset: offset, Length: length},
+		},
+	}
+}
+
+// Get the length of given string in UTF-16 code units.
+func utf16Length(str string) int {
+	return len(utf16.Encode([]rune(str)))
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		message Message
+		ok      bool
+		command Command
+	}{
+		{
+			name:    "without args",
+			message: commandMessage("/start", 0, 6),
+			ok:      true,
+			command: Command{Name: "start", Args: []string{}},
+		},
+		{
+			name:    "with username",
+			message: commandMessage("/start@MyBot hello world", 0, 12),
+			ok:      true,
+			command: Command{Name: "start", Username: "MyBot", Args: []string{"hello", "world"}, RawArgs: "hello world"},
+		},
+		{
+			name:    "with utf-16 surrogate pairs in args",
+			message: commandMessage("/echo 😀 \"a 😀\"", 0, 5),
+			ok:      true,
+			command: Command{Name: "echo", Args: []string{"😀", "a 😀"}, RawArgs: "😀 \"a 😀\""},
+		},
+		{
+			name:    "with utf-16 surrogate pairs in entity",
+			message: commandMessage("/😀x rest", 0, utf16Length("/😀x")),
+			ok:      true,
+			command: Command{Name: "😀x", Args: []string{"rest"}, RawArgs: "rest"},
+		},
+		{
+			name:    "with apostrophes",
+			message: commandMessage("/say I'm here", 0, 4),
+			ok:      true,
+			command: Command{Name: "say", Args: []string{"I'm", "here"}, RawArgs: "I'm here"},
+		},
+		{
+			name:    "not at the beginning",
+			message: commandMessage("hello /start", 6, 6),
+			ok:      false,
+		},
+		{
+			name:    "entity longer than text",
+			message: commandMessage("/start", 0, utf16Length("/start")+1),
+			ok:      false,
+		},
+		{
+			name:    "without text",
+			message: Message{},
+			ok:      false,
+		},
+	}
+
+	for _, test := range tests {
+		command, ok := ParseCommand(test.message)
+		if ok != test.ok {
+			t.Errorf("%s: expected ok = %v, but got %v", test.name, test.ok, ok)
+			continue
+		}
+		if ok && !reflect.DeepEqual(command, test.command) {
+			t.Errorf("%s: expected %#v, but got %#v", test.name, test.command, command)
+		}
+	}
+}
+
+func TestSplitCommandArgs(t *testing.T) {
+	tests := []struct {
+		str  string
+		args []string
+	}{
+		{``, []string{}},
+		{`a b  c`, []string{"a", "b", "c"}},
+		{`"hello world" again`, []string{"hello world", "again"}},
+		{`'single quoted' arg`, []string{"single quoted", "arg"}},
+		{`"say \"hello\""`, []string{`say "hello"`}},
+		{`I'm here`, []string{"I'm", "here"}},
+		{`don't worry be happy`, []string{"don't", "worry", "be", "happy"}},
+		{`it's "quoted words"`, []string{"it's", "quoted words"}},
+		{`say "hi`, []string{"say", `"hi`}},
+		{`say "hi there`, []string{"say", `"hi`, "there"}},
+		{`'a "b c"`, []string{"'a", "b c"}},
+	}
+
+	for _, test := range tests {
+		if args := splitCommandArgs(test.str); !reflect.DeepEqual(args, test.args) {
+			t.Errorf("%q: expected %q, but got %q", test.str, test.args, args)
+		}
+	}
+}
@@ -0,0 +1,554 @@
+package telegrambot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConversationKey is a key for identifying conversations. (a user in a chat)
+type ConversationKey struct {
+	ChatID int64
+	UserID int
+}
+
+// String function for ConversationKey
+func (k ConversationKey) String() string {
+	return fmt.Sprintf("%d:%d", k.ChatID, k.UserID)
+}
+
+// ConversationState is a state of a conversation.
+type ConversationState struct {
+	Name      string            `json:"name"`           // name of the current state
+	Data      map[string]string `json:"data,omitempty"` // data collected in the conversation
+	UpdatedAt time.Time         `json:"updated_at"`     // last time the state was updated
+}
+
+// ConversationStore is an interface for storing states of conversations.
+//
+// Users can continue their conversations after the bot restarts, when states are kept outside of the process. (eg. NewFileConversationStore)
+type ConversationStore interface {
+	// Get returns the state of given conversation.
+	Get(key ConversationKey) (state ConversationState, exists bool, err error)
+
+	// Set stores the state of given conversation.
+	Set(key ConversationKey, state ConversationState) error
+
+	// Delete deletes the state of given conversation.
+	Delete(key ConversationKey) error
+
+	// Keys returns the keys of all stored conversations. (for expiring idle ones)
+	Keys() (keys []ConversationKey, err error)
+}
+
+// in-memory implementation of ConversationStore
+type memoryConversationStore struct {
+	states *memoryMap
+}
+
+// NewMemoryConversationStore returns a new in-memory ConversationStore.
+func NewMemoryConversationStore() ConversationStore {
+	return &memoryConversationStore{
+		states: newMemoryMap(),
+	}
+}
+
+// Get returns the state of given conversation.
+func (s *memoryConversationStore) Get(key ConversationKey) (state ConversationState, exists bool, err error) {
+	var value interface{}
+	if value, exists = s.states.get(key); exists {
+		state = value.(ConversationState)
+	}
+
+	return state, exists, nil
+}
+
+// Set stores the state of given conversation.
+func (s *memoryConversationStore) Set(key ConversationKey, state ConversationState) error {
+	s.states.set(key, state)
+
+	return nil
+}
+
+// Delete deletes the state of given conversation.
+func (s *memoryConversationStore) Delete(key ConversationKey) error {
+	s.states.delete(key)
+
+	return nil
+}
+
+// Keys returns the keys of all stored conversations.
+func (s *memoryConversationStore) Keys() (keys []ConversationKey, err error) {
+	for _, key := range s.states.keys() {
+		keys = append(keys, key.(ConversationKey))
+	}
+
+	return keys, nil
+}
+
+// file-based implementation of ConversationStore
+type fileConversationStore struct {
+	states *jsonFileMap
+}
+
+// NewFileConversationStore returns a new ConversationStore which stores states of conversations in given file. (as JSON)
+func NewFileConversationStore(filepath string) ConversationStore {
+	return &fileConversationStore{
+		states: newJSONFileMap(filepath),
+	}
+}
+
+// Get returns the state of given conversation.
+func (s *fileConversationStore) Get(key ConversationKey) (state ConversationState, exists bool, err error) {
+	exists, err = s.states.get(key.String(), &state)
+
+	return state, exists, err
+}
+
+// Set stores the state of given conversation.
+func (s *fileConversationStore) Set(key ConversationKey, state ConversationState) error {
+	return s.states.set(key.String(), state)
+}
+
+// Delete deletes the state of given conversation.
+func (s *fileConversationStore) Delete(key ConversationKey) error {
+	return s.states.delete(key.String())
+}
+
+// Keys returns the keys of all stored conversations.
+func (s *fileConversationStore) Keys() (keys []ConversationKey, err error) {
+	var strs []string
+	if strs, err = s.states.keys(); err != nil {
+		return nil, err
+	}
+
+	for _, str := range strs {
+		var key ConversationKey
+		if _, err = fmt.Sscanf(str, "%d:%d", &key.ChatID, &key.UserID); err != nil {
+			return nil, fmt.Errorf("malformed conversation key '%s' (%s)", str, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// Conversation is a conversation passed to conversation handlers.
+type Conversation struct {
+	Key   ConversationKey
+	State string            // name of the current state (empty in entry handlers)
+	Data  map[string]string // data collected in the conversation (changes will be stored)
+
+	update Update
+	next   *string
+	ended  bool
+}
+
+// Next sets the next state of the conversation.
+func (c *Conversation) Next(state string) {
+	c.next = &state
+}
+
+// End ends the conversation, and deletes its state.
+func (c *Conversation) End() {
+	c.ended = true
+}
+
+// Ask sends given text as a reply which forces the user to reply, and sets the next state of the conversation.
+//
+// https://core.telegram.org/bots/api#forcereply
+func (c *Conversation) Ask(b *Bot, text string, nextState string) (result APIResponseMessage) {
+	c.Next(nextState)
+
+	return b.SendMessage(c.Key.ChatID, text, c.replyOptions().
+		SetReplyMarkup(ForceReply{
+			ForceReply: true,
+			Selective:  true,
+		}))
+}
+
+// AskWithKeyboard sends given text with a one-time reply keyboard of given choices, and sets the next state of the conversation.
+//
+// https://core.telegram.org/bots/api#replykeyboardmarkup
+func (c *Conversation) AskWithKeyboard(b *Bot, text string, keyboard [][]KeyboardButton, nextState string) (result APIResponseMessage) {
+	c.Next(nextState)
+
+	return b.SendMessage(c.Key.ChatID, text, c.replyOptions().
+		SetReplyMarkup(ReplyKeyboardMarkup{
+			Keyboard:        keyboard,
+			ResizeKeyboard:  true,
+			OneTimeKeyboard: true,
+			Selective:       true,
+		}))
+}
+
+// Finish sends given text with removing the reply keyboard, and ends the conversation.
+//
+// https://core.telegram.org/bots/api#replykeyboardremove
+func (c *Conversation) Finish(b *Bot, text string) (result APIResponseMessage) {
+	c.End()
+
+	return b.SendMessage(c.Key.ChatID, text, c.replyOptions().
+		SetReplyMarkup(ReplyKeyboardRemove{
+			RemoveKeyboard: true,
+			Selective:      true,
+		}))
+}
+
+// Options for replying to the message of the current update. (for selective reply markups in groups)
+func (c *Conversation) replyOptions() OptionsSendMessage {
+	options := OptionsSendMessage{}
+	if c.update.Message != nil {
+		options.SetReplyToMessageID(c.update.Message.MessageID)
+	}
+
+	return options
+}
+
+// ConversationHandler is a function type for handling updates in conversations.
+type ConversationHandler func(b *Bot, update Update, conversation *Conversation)
+
+// ConversationManager manages multi-step conversations with named states.
+//
+// Conversations are started with entry point commands, and kept per user in each chat.
+// Each update in a conversation is routed to the handler of its current state,
+// and fallback commands (eg. "/cancel") are checked first in all states.
+//
+// Updates in the same conversation are handled one by one (in this process), so that no state transition will be lost.
+type ConversationManager struct {
+	mutex     sync.RWMutex
+	store     ConversationStore
+	entries   map[string]ConversationHandler
+	states    map[string][]conversationRoute
+	fallbacks map[string]ConversationHandler
+	timeout   time.Duration
+	onTimeout ConversationHandler
+
+	locksMutex sync.Mutex                            // mutex for locks
+	locks      map[ConversationKey]*conversationLock // locks of conversations being handled
+
+	expiringMutex sync.Mutex // mutex for expiring and expired
+	expiring      bool       // whether idle conversations are being expired or not
+	expired       time.Time  // last time idle conversations were expired
+}
+
+// a lock of a conversation, with the number of goroutines holding or waiting for it
+type conversationLock struct {
+	mutex sync.Mutex
+	refs  int
+}
+
+// a state handler registered in ConversationManager
+type conversationRoute struct {
+	filter  Filter
+	handler ConversationHandler
+}
+
+// NewConversationManager returns a new ConversationManager with given store. (if nil, an in-memory one will be used)
+func NewConversationManager(store ConversationStore) *ConversationManager {
+	if store == nil {
+		store = NewMemoryConversationStore()
+	}
+
+	return &ConversationManager{
+		store:     store,
+		entries:   map[string]ConversationHandler{},
+		states:    map[string][]conversationRoute{},
+		fallbacks: map[string]ConversationHandler{},
+		locks:     map[ConversationKey]*conversationLock{},
+	}
+}
+
+// Entry registers an entry point command (eg. "/start" or "start") of conversations.
+//
+// Given handler should set the next state with Conversation.Next (or Ask) for starting a conversation.
+func (m *ConversationManager) Entry(command string, handler ConversationHandler) *ConversationManager {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.entries[normalizeCommandName(command)] = handler
+
+	return m
+}
+
+// State registers a handler for given state, which will be called when all of given filters match.
+//
+// Multiple handlers with filters can be registered for a state, and the first one with matching filters will be called.
+func (m *ConversationManager) State(state string, handler ConversationHandler, filters ...Filter) *ConversationManager {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.states[state] = append(m.states[state], conversationRoute{
+		filter:  allFilters(filters),
+		handler: handler,
+	})
+
+	return m
+}
+
+// Fallback registers a command (eg. "/cancel") which can be used in all states of conversations.
+func (m *ConversationManager) Fallback(command string, handler ConversationHandler) *ConversationManager {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.fallbacks[normalizeCommandName(command)] = handler
+
+	return m
+}
+
+// SetTimeout sets the timeout of inactive conversations, and a handler which will be called with the timed-out ones. (handler can be nil)
+//
+// Timeouts are checked when the next update of the conversation arrives,
+// and all stored conversations are checked in background while routing updates. (at most once per timeout)
+// Call ExpireIdle periodically for expiring them even when no update arrives.
+func (m *ConversationManager) SetTimeout(timeout time.Duration, handler ConversationHandler) *ConversationManager {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.timeout = timeout
+	m.onTimeout = handler
+
+	return m
+}
+
+// HandleUpdate routes given update to the handler of its conversation.
+//
+// It can be used as an update handler, eg. `client.StartLongPollingUpdates(0, 50, manager.HandleUpdate)`.
+func (m *ConversationManager) HandleUpdate(b *Bot, update Update, err error) {
+	if err != nil {
+		b.error("error while receiving update (%s)", err)
+		return
+	}
+
+	m.Route(b, update)
+}
+
+// Middleware returns a middleware which routes updates in conversations (and entry point commands) to this manager,
+// and passes the others to the next handler.
+func (m *ConversationManager) Middleware() Middleware {
+	return func(next UpdateHandler) UpdateHandler {
+		return func(b *Bot, update Update, err error) {
+			if err == nil && m.Route(b, update) {
+				return
+			}
+
+			next(b, update, err)
+		}
+	}
+}
+
+// Route routes given update to the handler of its conversation, and returns whether it was handled or not.
+func (m *ConversationManager) Route(b *Bot, update Update) (handled bool) {
+	key, ok := conversationKey(update)
+	if !ok {
+		return false
+	}
+
+	// handle updates of the same conversation one by one, from getting its state to storing the changed one
+	unlock := m.lock(key)
+	defer unlock()
+
+	state, exists, err := m.store.Get(key)
+	if err != nil {
+		b.error("failed to get state of conversation %s (%s)", key, err)
+		return false
+	}
+
+	m.expireIdleInBackground(b)
+
+	// check timeout
+	if exists && m.expireIfIdle(b, key, state, update) {
+		exists = false
+	}
+
+	var command *Command
+	if message := update.Message; message != nil {
+		if parsed, ok := ParseCommand(*message); ok {
+			command = &parsed
+		}
+	}
+
+	var handler ConversationHandler
+	m.mutex.RLock()
+	if exists {
+		if command != nil {
+			handler = m.fallbacks[strings.ToLower(command.Name)]
+		}
+		if handler == nil {
+			for _, route := range m.states[state.Name] {
+				if route.filter(b, update) {
+					handler = route.handler
+					break
+				}
+			}
+		}
+	} else if command != nil {
+		handler = m.entries[strings.ToLower(command.Name)]
+		state = ConversationState{}
+	}
+	m.mutex.RUnlock()
+
+	if handler == nil {
+		return false
+	}
+
+	conversation := newConversation(key, state, update)
+	handler(b, update, conversation)
+
+	// store the changed state
+	if conversation.ended || (!exists && conversation.next == nil) {
+		if exists {
+			if err := m.store.Delete(key); err != nil {
+				b.error("failed to delete state of conversation %s (%s)", key, err)
+			}
+		}
+	} else {
+		if conversation.next != nil {
+			state.Name = *conversation.next
+		}
+		state.Data = conversation.Data
+		state.UpdatedAt = time.Now()
+
+		if err := m.store.Set(key, state); err != nil {
+			b.error("failed to set state of conversation %s (%s)", key, err)
+		}
+	}
+
+	return true
+}
+
+// ExpireIdle deletes the states of conversations which are inactive longer than the timeout, and calls the timeout handler with them.
+//
+// The timeout handler will be called with an empty update.
+func (m *ConversationManager) ExpireIdle(b *Bot) {
+	keys, err := m.store.Keys()
+	if err != nil {
+		b.error("failed to get keys of conversations (%s)", err)
+		return
+	}
+
+	for _, key := range keys {
+		func() {
+			unlock := m.lock(key)
+			defer unlock()
+
+			if state, exists, err := m.store.Get(key); err != nil {
+				b.error("failed to get state of conversation %s (%s)", key, err)
+			} else if exists {
+				m.expireIfIdle(b, key, state, Update{})
+			}
+		}()
+	}
+}
+
+// Expire idle conversations in background, if the timeout has passed since the last time.
+func (m *ConversationManager) expireIdleInBackground(b *Bot) {
+	m.mutex.RLock()
+	timeout := m.timeout
+	m.mutex.RUnlock()
+
+	if timeout <= 0 {
+		return
+	}
+
+	m.expiringMutex.Lock()
+	defer m.expiringMutex.Unlock()
+
+	if m.expiring || time.Since(m.expired) < timeout {
+		return
+	}
+	m.expiring = true
+
+	go func() {
+		m.ExpireIdle(b)
+
+		m.expiringMutex.Lock()
+		defer m.expiringMutex.Unlock()
+
+		m.expiring = false
+		m.expired = time.Now()
+	}()
+}
+
+// Delete given state of conversation and call the timeout handler, if it is inactive longer than the timeout.
+//
+// It should be called while holding the lock of the conversation. Returns true if it was expired.
+func (m *ConversationManager) expireIfIdle(b *Bot, key ConversationKey, state ConversationState, update Update) (expired bool) {
+	m.mutex.RLock()
+	timeout, onTimeout := m.timeout, m.onTimeout
+	m.mutex.RUnlock()
+
+	if timeout <= 0 || time.Since(state.UpdatedAt) <= timeout {
+		return false
+	}
+
+	b.verbose("conversation %s timed out in state: %s", key, state.Name)
+
+	if err := m.store.Delete(key); err != nil {
+		b.error("failed to delete state of conversation %s (%s)", key, err)
+	}
+	if onTimeout != nil {
+		onTimeout(b, update, newConversation(key, state, update))
+	}
+
+	return true
+}
+
+// Lock the conversation with given key, and return a function for unlocking it.
+func (m *ConversationManager) lock(key ConversationKey) (unlock func()) {
+	m.locksMutex.Lock()
+	l, exists := m.locks[key]
+	if !exists {
+		l = &conversationLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.locksMutex.Unlock()
+
+	l.mutex.Lock()
+
+	return func() {
+		l.mutex.Unlock()
+
+		m.locksMutex.Lock()
+		defer m.locksMutex.Unlock()
+
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+	}
+}
+
+// Create a new conversation with given key and state.
+func newConversation(key ConversationKey, state ConversationState, update Update) *Conversation {
+	data := map[string]string{}
+	for k, v := range state.Data {
+		data[k] = v
+	}
+
+	return &Conversation{
+		Key:    key,
+		State:  state.Name,
+		Data:   data,
+		update: update,
+	}
+}
+
+// Get the key of conversation for given update. (only messages and callback queries are part of conversations)
+func conversationKey(update Update) (key ConversationKey, ok bool) {
+	if update.Message == nil && update.CallbackQuery == nil {
+		return ConversationKey{}, false
+	}
+
+	message, from := updateMessage(update), updateSender(update)
+	if message == nil || from == nil {
+		return ConversationKey{}, false
+	}
+
+	return ConversationKey{
+		ChatID: message.Chat.ID,
+		UserID: from.ID,
+	}, true
+}
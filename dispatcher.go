@@ -0,0 +1,203 @@
+package telegrambot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// UpdateHandler is a function type for handling updates (from both polling and webhook).
+type UpdateHandler func(b *Bot, update Update, err error)
+
+// an update queued in UpdateDispatcher
+type queuedUpdate struct {
+	bot     *Bot
+	handler UpdateHandler
+	update  Update
+	err     error
+	done    func(handled bool) // called after the handler returns, or when the update could not be dispatched (can be nil)
+}
+
+// UpdateDispatcher dispatches updates to a fixed number of workers through a bounded queue.
+//
+// Updates of the same chat are handled sequentially in the order they were received,
+// while updates of different chats are handled in parallel.
+type UpdateDispatcher struct {
+	queues  []chan queuedUpdate
+	workers sync.WaitGroup
+	next    uint32 // for distributing updates without chats
+
+	mutex   sync.RWMutex
+	stopped bool
+}
+
+// NewUpdateDispatcher returns a new UpdateDispatcher with given number of workers and queue size,
+// and starts its workers.
+//
+// When the queue is full, dispatching updates will be blocked until there is room. (backpressure)
+func NewUpdateDispatcher(numWorkers, queueSize int) *UpdateDispatcher {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	queueSizePerWorker := (queueSize + numWorkers - 1) / numWorkers
+	if queueSizePerWorker < 1 {
+		queueSizePerWorker = 1
+	}
+
+	d := &UpdateDispatcher{
+		queues: make([]chan queuedUpdate, numWorkers),
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan queuedUpdate, queueSizePerWorker)
+
+		d.workers.Add(1)
+		go d.work(d.queues[i])
+	}
+
+	return d
+}
+
+// QueueDepth returns the number of updates waiting in the queue.
+func (d *UpdateDispatcher) QueueDepth() (depth int) {
+	for _, queue := range d.queues {
+		depth += len(queue)
+	}
+
+	return depth
+}
+
+// Stop stops accepting new updates, and waits for queued updates to be handled.
+func (d *UpdateDispatcher) Stop() {
+	d.mutex.Lock()
+	if !d.stopped {
+		d.stopped = true
+
+		for _, queue := range d.queues {
+			close(queue)
+		}
+	}
+	d.mutex.Unlock()
+
+	d.workers.Wait()
+}
+
+// Enqueue given update. Blocks while the queue is full, or until given context is done.
+func (d *UpdateDispatcher) dispatch(ctx context.Context, queued queuedUpdate) error {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if d.stopped {
+		return fmt.Errorf("update dispatcher is already stopped")
+	}
+
+	var index int
+	if chatID, exists := updateChatID(queued.update); exists {
+		if chatID < 0 {
+			chatID = -chatID
+		}
+		index = int(chatID % int64(len(d.queues)))
+	} else {
+		index = int(atomic.AddUint32(&d.next, 1) % uint32(len(d.queues)))
+	}
+
+	select {
+	case d.queues[index] <- queued:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Handle updates from given queue.
+func (d *UpdateDispatcher) work(queue chan queuedUpdate) {
+	defer d.workers.Done()
+
+	for queued := range queue {
+		queued.handle()
+	}
+}
+
+// Call the handler with the queued update.
+//
+// Panics in the handler are recovered and logged, so that they will not crash the process.
+func (q queuedUpdate) handle() {
+	if q.done != nil {
+		defer q.done(true)
+	}
+	if q.err == nil && q.update.CallbackQuery != nil && q.bot.autoAnswerCallbackQueries {
+		q.bot.trackCallbackQuery(q.update.CallbackQuery.ID)
+		defer q.bot.answerCallbackQueryIfNeeded(q.update.CallbackQuery.ID)
+	}
+	defer q.bot.recoverHandlerPanic(q.update, nil)
+
+	q.handler(q.bot, q.update, q.err)
+}
+
+// Get the id of chat (or user, when there is no chat) of given update, for keeping the order of updates.
+func updateChatID(update Update) (chatID int64, exists bool) {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat.ID, true
+	case update.EditedMessage != nil:
+		return update.EditedMessage.Chat.ID, true
+	case update.ChannelPost != nil:
+		return update.ChannelPost.Chat.ID, true
+	case update.EditedChannelPost != nil:
+		return update.EditedChannelPost.Chat.ID, true
+	case update.CallbackQuery != nil:
+		if update.CallbackQuery.Message != nil {
+			return update.CallbackQuery.Message.Chat.ID, true
+		}
+		return int64(update.CallbackQuery.From.ID), true
+	case update.InlineQuery != nil:
+		return int64(update.InlineQuery.From.ID), true
+	case update.ChosenInlineResult != nil:
+		return int64(update.ChosenInlineResult.From.ID), true
+	case update.ShippingQuery != nil:
+		return int64(update.ShippingQuery.From.ID), true
+	case update.PreCheckoutQuery != nil:
+		return int64(update.PreCheckoutQuery.From.ID), true
+	}
+
+	return 0, false
+}
+
+// SetUpdateDispatcher sets the dispatcher for handling updates from both polling and webhook.
+// (nil for handling each update in a new goroutine when polling, or synchronously in webhook requests, which is the default)
+func (b *Bot) SetUpdateDispatcher(dispatcher *UpdateDispatcher) {
+	b.updateDispatcher = dispatcher
+}
+
+// Dispatch given update to the update handler (wrapped with middlewares) through the dispatcher of this bot.
+//
+// When no dispatcher is set, the handler is called in a new goroutine (or synchronously, if sync is true).
+func (b *Bot) dispatchUpdate(ctx context.Context, handler UpdateHandler, update Update, err error, done func(handled bool), sync bool) error {
+	queued := queuedUpdate{
+		bot:     b,
+		handler: b.wrapHandler(handler),
+		update:  update,
+		err:     err,
+		done:    done,
+	}
+
+	if b.updateDispatcher != nil {
+		if err := b.updateDispatcher.dispatch(ctx, queued); err != nil {
+			if done != nil {
+				done(false)
+			}
+
+			return err
+		}
+
+		return nil
+	}
+
+	if sync {
+		queued.handle()
+	} else {
+		go queued.handle()
+	}
+
+	return nil
+}
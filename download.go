@@ -0,0 +1,215 @@
+package telegrambot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// File.FilePath is guaranteed to be valid for at least 1 hour.
+//
+// https://core.telegram.org/bots/api#file
+const filePathLifetime = 1 * time.Hour
+
+// info of a file fetched with GetFile
+type cachedFile struct {
+	file      File
+	fetchedAt time.Time
+}
+
+// DownloadFile fetches the info of file with given id, and returns a reader of its contents.
+//
+// The returned reader streams the contents, and should be closed after use.
+// It fails when the number of read bytes does not match File.FileSize.
+//
+// File.FilePath is cached until it goes stale (1 hour), and is fetched again when it could not be found.
+// If the bot API server is running in local mode, the file will be read from local disk.
+func (b *Bot) DownloadFile(fileID string) (reader io.ReadCloser, err error) {
+	return b.DownloadFileContext(context.Background(), fileID)
+}
+
+// DownloadFileContext is the same as DownloadFile, but with given context.
+//
+// Reading from the returned reader will fail when given context is canceled or its deadline is exceeded.
+func (b *Bot) DownloadFileContext(ctx context.Context, fileID string) (reader io.ReadCloser, err error) {
+	for refetch := false; ; refetch = true {
+		var file File
+		var cached bool
+		if file, cached, err = b.getFileForDownload(ctx, fileID, refetch); err != nil {
+			return nil, &DownloadError{FileID: fileID, Err: err}
+		}
+
+		var body io.ReadCloser
+		var statusCode int
+		var notFound bool
+		if body, statusCode, notFound, err = b.openFile(ctx, file); err != nil {
+			if cached && notFound {
+				b.verbose("file path of %s was not found, fetching it again", fileID)
+
+				continue
+			}
+
+			return nil, &DownloadError{FileID: fileID, StatusCode: statusCode, Err: b.redactError(err)}
+		}
+
+		return &downloadReader{
+			bot:        b,
+			ctx:        ctx,
+			body:       body,
+			fileID:     fileID,
+			statusCode: statusCode,
+			size:       int64(file.FileSize),
+		}, nil
+	}
+}
+
+// DownloadFileTo downloads the file with given id, and writes its contents to given writer.
+//
+// See DownloadFile for details.
+func (b *Bot) DownloadFileTo(fileID string, writer io.Writer) (written int64, err error) {
+	return b.DownloadFileToContext(context.Background(), fileID, writer)
+}
+
+// DownloadFileToContext is the same as DownloadFileTo, but with given context.
+func (b *Bot) DownloadFileToContext(ctx context.Context, fileID string, writer io.Writer) (written int64, err error) {
+	var reader io.ReadCloser
+	if reader, err = b.DownloadFileContext(ctx, fileID); err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	return io.Copy(writer, reader)
+}
+
+// DownloadFileToPath downloads the file with given id, and saves its contents to given filepath.
+//
+// The file is written to a temporary file first, so it will not be left incomplete on failures.
+// It will be created with permission 0644.
+//
+// See DownloadFile for details.
+func (b *Bot) DownloadFileToPath(fileID, filepath string) (err error) {
+	return b.DownloadFileToPathContext(context.Background(), fileID, filepath)
+}
+
+// DownloadFileToPathContext is the same as DownloadFileToPath, but with given context.
+func (b *Bot) DownloadFileToPathContext(ctx context.Context, fileID, filepath string) (err error) {
+	return writeFileAtomicallyWith(filepath, 0644, func(w io.Writer) error {
+		_, err := b.DownloadFileToContext(ctx, fileID, w)
+		return err
+	})
+}
+
+// Get the info of file with given id for downloading, from the cache if its path is not stale yet.
+//
+// When refetch is true, it will be fetched with GetFile regardless of the cache.
+func (b *Bot) getFileForDownload(ctx context.Context, fileID string, refetch bool) (file File, cached bool, err error) {
+	if !refetch {
+		b.filesMutex.Lock()
+		f, exists := b.files[fileID]
+		b.filesMutex.Unlock()
+
+		if exists && time.Since(f.fetchedAt) < filePathLifetime {
+			return f.file, true, nil
+		}
+	}
+
+	result := b.GetFileContext(ctx, fileID)
+	if err = result.Err(); err != nil {
+		return File{}, false, err
+	}
+	if result.Result == nil || result.Result.FilePath == nil {
+		return File{}, false, fmt.Errorf("no file path was returned")
+	}
+	file = *result.Result
+
+	b.filesMutex.Lock()
+	defer b.filesMutex.Unlock()
+
+	now := time.Now()
+	if b.files == nil {
+		b.files = map[string]cachedFile{}
+	}
+	for id, f := range b.files { // remove stale ones
+		if now.Sub(f.fetchedAt) >= filePathLifetime {
+			delete(b.files, id)
+		}
+	}
+	b.files[fileID] = cachedFile{file: file, fetchedAt: now}
+
+	return file, false, nil
+}
+
+// Open the contents of given file, from the file server or from local disk. (in local mode)
+//
+// notFound will be true when the file does not exist (anymore) at its path.
+func (b *Bot) openFile(ctx context.Context, file File) (body io.ReadCloser, statusCode int, notFound bool, err error) {
+	if b.localMode {
+		var f *os.File
+		if f, err = os.Open(*file.FilePath); err != nil {
+			return nil, 0, os.IsNotExist(err), err
+		}
+
+		return f, 0, false, nil
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequest("GET", b.GetFileURL(file), nil); err != nil {
+		return nil, 0, false, err
+	}
+
+	var resp *http.Response
+	if resp, err = b.httpClient.Do(req.WithContext(ctx)); err != nil {
+		return nil, 0, false, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+
+		return nil, resp.StatusCode, resp.StatusCode == http.StatusNotFound, fmt.Errorf("http status %d", resp.StatusCode)
+	}
+
+	return resp.Body, resp.StatusCode, false, nil
+}
+
+// a reader of downloaded file, which verifies its size and stops on context cancellation
+type downloadReader struct {
+	bot        *Bot
+	ctx        context.Context
+	body       io.ReadCloser
+	fileID     string
+	statusCode int
+	size       int64 // expected size in bytes (0 if unknown)
+	read       int64
+}
+
+// Read reads the contents of downloaded file.
+func (r *downloadReader) Read(p []byte) (n int, err error) {
+	if err = r.ctx.Err(); err != nil {
+		return 0, r.error(err)
+	}
+
+	n, err = r.body.Read(p)
+	r.read += int64(n)
+
+	if r.size > 0 && (r.read > r.size || (err == io.EOF && r.read < r.size)) {
+		return n, r.error(fmt.Errorf("size mismatch: expected %d bytes, but read %d bytes", r.size, r.read))
+	}
+	if err != nil && err != io.EOF {
+		return n, r.error(r.bot.redactError(err))
+	}
+
+	return n, err
+}
+
+// Close closes the body of downloaded file.
+func (r *downloadReader) Close() error {
+	return r.body.Close()
+}
+
+// Wrap given error as a DownloadError.
+func (r *downloadReader) error(err error) error {
+	return &DownloadError{FileID: r.fileID, StatusCode: r.statusCode, Err: err}
+}
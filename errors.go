@@ -0,0 +1,100 @@
+package telegrambot
+
+import (
+	"fmt"
+	"time"
+)
+
+// APIError is an error returned from the bot API server.
+//
+// https://core.telegram.org/bots/api#making-requests
+type APIError struct {
+	StatusCode  int                    // http status code of the response
+	ErrorCode   int                    // `error_code` of the response
+	Description string                 // `description` of the response
+	Parameters  *APIResponseParameters // `parameters` of the response (can be nil)
+}
+
+// Error returns the error message of APIError.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error (%d): %s", e.ErrorCode, e.Description)
+}
+
+// RetryAfter returns the duration to wait before the request can be repeated. (0 if not given)
+func (e *APIError) RetryAfter() time.Duration {
+	if e.Parameters == nil {
+		return 0
+	}
+
+	return time.Duration(e.Parameters.RetryAfter) * time.Second
+}
+
+// MigrateToChatID returns the new chat id of a group which was migrated to a supergroup. (0 if not given)
+func (e *APIError) MigrateToChatID() int64 {
+	if e.Parameters == nil {
+		return 0
+	}
+
+	return e.Parameters.MigrateToChatID
+}
+
+// RequestError is an error which occurred while sending a request to the bot API server,
+// or while handling its response. (eg. network errors, timeouts, or malformed responses)
+type RequestError struct {
+	Method     string // name of the requested method
+	StatusCode int    // http status code of the response (0 if no response was received)
+	Err        error  // underlying error
+}
+
+// Error returns the error message of RequestError.
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%s failed with error: %s", e.Method, e.Err)
+}
+
+// Unwrap returns the underlying error of RequestError.
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimitError is an error returned when a request was not sent due to the rate limiter of the bot.
+// (only in RateLimitModeTry)
+type RateLimitError struct {
+	Method string // name of the requested method
+	ChatID ChatID // `chat_id` of the request
+}
+
+// Error returns the error message of RateLimitError.
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s to chat %v was not sent due to rate limit", e.Method, e.ChatID)
+}
+
+// CallbackDataError is an error returned when callback data could not be encoded or decoded.
+// (eg. too long, malformed, or has an invalid signature)
+type CallbackDataError struct {
+	Data   string // callback data
+	Reason string // reason of the error
+}
+
+// Error returns the error message of CallbackDataError.
+func (e *CallbackDataError) Error() string {
+	return fmt.Sprintf("invalid callback data '%s': %s", e.Data, e.Reason)
+}
+
+// DownloadError is an error which occurred while downloading a file.
+//
+// Its message never contains the token of the bot.
+type DownloadError struct {
+	FileID     string // id of the file
+	StatusCode int    // http status code of the response (0 if no response was received, or in local mode)
+	Err        error  // underlying error
+}
+
+// Error returns the error message of DownloadError.
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("download of file %s failed with error: %s", e.FileID, e.Err)
+}
+
+// Unwrap returns the underlying error of DownloadError.
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,272 @@
+package telegrambot
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileIDCache is an interface for caching file ids of uploaded files, so that the same files are not uploaded again.
+//
+// Keys are generated from the methods and the files (see SetFileIDCache), and file ids are the ones returned from the server.
+// File ids stay valid across restarts, so caching them in a file (or a database) saves uploads after restarts too.
+type FileIDCache interface {
+	// Get returns the file id cached with given key.
+	Get(key string) (fileID string, exists bool, err error)
+
+	// Set caches given file id with given key.
+	Set(key, fileID string) error
+
+	// Delete deletes the file id cached with given key.
+	Delete(key string) error
+}
+
+// in-memory implementation of FileIDCache
+type memoryFileIDCache struct {
+	fileIDs *memoryMap
+}
+
+// NewMemoryFileIDCache returns a new in-memory FileIDCache.
+func NewMemoryFileIDCache() FileIDCache {
+	return &memoryFileIDCache{
+		fileIDs: newMemoryMap(),
+	}
+}
+
+// Get returns the file id cached with given key.
+func (c *memoryFileIDCache) Get(key string) (fileID string, exists bool, err error) {
+	var value interface{}
+	if value, exists = c.fileIDs.get(key); exists {
+		fileID = value.(string)
+	}
+
+	return fileID, exists, nil
+}
+
+// Set caches given file id with given key.
+func (c *memoryFileIDCache) Set(key, fileID string) error {
+	c.fileIDs.set(key, fileID)
+
+	return nil
+}
+
+// Delete deletes the file id cached with given key.
+func (c *memoryFileIDCache) Delete(key string) error {
+	c.fileIDs.delete(key)
+
+	return nil
+}
+
+// file-based implementation of FileIDCache
+type fileFileIDCache struct {
+	fileIDs *jsonFileMap
+}
+
+// NewFileFileIDCache returns a new FileIDCache which stores file ids in given file. (as JSON)
+func NewFileFileIDCache(filepath string) FileIDCache {
+	return &fileFileIDCache{
+		fileIDs: newJSONFileMap(filepath),
+	}
+}
+
+// Get returns the file id cached with given key.
+func (c *fileFileIDCache) Get(key string) (fileID string, exists bool, err error) {
+	exists, err = c.fileIDs.get(key, &fileID)
+
+	return fileID, exists, err
+}
+
+// Set caches given file id with given key.
+func (c *fileFileIDCache) Set(key, fileID string) error {
+	return c.fileIDs.set(key, fileID)
+}
+
+// Delete deletes the file id cached with given key.
+func (c *fileFileIDCache) Delete(key string) error {
+	return c.fileIDs.delete(key)
+}
+
+// SetFileIDCache sets the cache for file ids of uploaded files. (nil for disabling, which is the default)
+//
+// When set, files sent with SendPhoto, SendAudio, SendDocument, SendSticker, SendVideo, SendAnimation, SendVoice, and SendVideoNote
+// are cached with their file ids after the first successful upload, and are sent with the cached file ids afterwards.
+//
+// Files from InputFileFromFilepath are identified by their absolute paths, modification times, and sizes,
+// and ones from InputFileFromBytes are identified by the hashes of their contents. (readers are not cached)
+// When a cached file id is rejected by the server, it will be deleted from the cache and the file will be uploaded again.
+func (b *Bot) SetFileIDCache(cache FileIDCache) {
+	b.fileIDCache = cache
+}
+
+// uploadable media of a method, and a function for getting the file id of it from the sent message
+type fileIDCacheableMedia struct {
+	param  string
+	fileID func(m *Message) string
+}
+
+// methods whose uploaded media can be cached with file ids
+var fileIDCacheableMethods = map[string]fileIDCacheableMedia{
+	"sendPhoto": {"photo", func(m *Message) string {
+		var largest *PhotoSize
+		for i, photo := range m.Photo {
+			if largest == nil || photo.Width*photo.Height > largest.Width*largest.Height ||
+				(photo.Width*photo.Height == largest.Width*largest.Height && photo.FileSize > largest.FileSize) {
+				largest = &m.Photo[i]
+			}
+		}
+		if largest != nil {
+			return largest.FileID
+		}
+		return ""
+	}},
+	"sendAudio": {"audio", func(m *Message) string {
+		if m.Audio != nil {
+			return m.Audio.FileID
+		}
+		return ""
+	}},
+	"sendDocument": {"document", func(m *Message) string {
+		if m.Document != nil {
+			return m.Document.FileID
+		}
+		return ""
+	}},
+	"sendSticker": {"sticker", func(m *Message) string {
+		if m.Sticker != nil {
+			return m.Sticker.FileID
+		}
+		return ""
+	}},
+	"sendVideo": {"video", func(m *Message) string {
+		if m.Video != nil {
+			return m.Video.FileID
+		}
+		return ""
+	}},
+	"sendAnimation": {"animation", func(m *Message) string {
+		if m.Animation != nil {
+			return m.Animation.FileID
+		}
+		return ""
+	}},
+	"sendVoice": {"voice", func(m *Message) string {
+		if m.Voice != nil {
+			return m.Voice.FileID
+		}
+		return ""
+	}},
+	"sendVideoNote": {"video_note", func(m *Message) string {
+		if m.VideoNote != nil {
+			return m.VideoNote.FileID
+		}
+		return ""
+	}},
+}
+
+// Generate the key of given param for caching its file id with given method. (empty if it cannot be cached)
+func fileIDCacheKey(method string, param interface{}) (key string, err error) {
+	file, ok := param.(InputFile)
+	if !ok {
+		return "", nil
+	}
+
+	switch {
+	case file.Filepath != nil:
+		var path string
+		if path, err = filepath.Abs(*file.Filepath); err != nil {
+			return "", err
+		}
+
+		var info os.FileInfo
+		if info, err = os.Stat(path); err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s:path:%s:%d:%d:%s", method, path, info.ModTime().UnixNano(), info.Size(), file.Filename), nil
+	case len(file.Bytes) > 0:
+		return fmt.Sprintf("%s:sha256:%x:%s", method, sha256.Sum256(file.Bytes), file.Filename), nil
+	}
+
+	return "", nil
+}
+
+// Send request for APIResponseMessage, with the file id cached for its uploadable media. (if any)
+//
+// After a successful upload, the file id of the sent media will be cached.
+func (b *Bot) requestResponseMessageWithFileIDCache(ctx context.Context, method string, params map[string]interface{}) (result APIResponseMessage) {
+	var key string
+	media, cacheable := fileIDCacheableMethods[method]
+	if cacheable {
+		var err error
+		if key, err = fileIDCacheKey(method, params[media.param]); err != nil {
+			b.error("failed to generate file id cache key for %s (%s)", method, err)
+		}
+	}
+	if key == "" {
+		b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
+
+		return result
+	}
+
+	if fileID, exists, err := b.fileIDCache.Get(key); err != nil {
+		b.error("failed to get cached file id for %s (%s)", method, err)
+	} else if exists {
+		cachedParams := map[string]interface{}{}
+		for k, v := range params {
+			cachedParams[k] = v
+		}
+		cachedParams[media.param] = InputFileFromFileID(fileID)
+
+		b.requestAndParse(ctx, method, cachedParams, &result, &result.APIResponseBase)
+		if !isFileIDRejected(result.Err()) {
+			return result
+		}
+
+		// the cached file id is not valid anymore, so upload the file again
+		b.verbose("cached file id for %s was rejected, uploading again", method)
+
+		if err := b.fileIDCache.Delete(key); err != nil {
+			b.error("failed to delete cached file id for %s (%s)", method, err)
+		}
+		result = APIResponseMessage{}
+	}
+
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
+
+	if result.Ok && result.Result != nil {
+		if fileID := media.fileID(result.Result); fileID != "" {
+			if err := b.fileIDCache.Set(key, fileID); err != nil {
+				b.error("failed to cache file id for %s (%s)", method, err)
+			}
+		}
+	}
+
+	return result
+}
+
+// descriptions of errors returned for invalid (or expired) file ids, in lower case
+var fileIDRejectedDescriptions = []string{
+	"wrong file identifier",        // eg. "Bad Request: wrong file identifier/HTTP URL specified"
+	"wrong remote file identifier", // eg. "Bad Request: wrong remote file identifier specified: ..."
+	"wrong file_id",                // eg. "Bad Request: wrong file_id or the file is temporarily unavailable"
+	"file reference expired",
+	"file_reference_expired",
+	"file_id_invalid",
+}
+
+// Check if given error was returned from the server due to an invalid (or expired) file id.
+func isFileIDRejected(err error) bool {
+	if apiErr, ok := err.(*APIError); ok && apiErr.ErrorCode == 400 {
+		description := strings.ToLower(apiErr.Description)
+		for _, rejected := range fileIDRejectedDescriptions {
+			if strings.Contains(description, rejected) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,32 @@
+package telegrambot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsFileIDRejected(t *testing.T) {
+	tests := []struct {
+		err      error
+		rejected bool
+	}{
+		{&APIError{ErrorCode: 400, Description: "Bad Request: wrong file identifier/HTTP URL specified"}, true},
+		{&APIError{ErrorCode: 400, Description: "Bad Request: wrong remote file identifier specified: Wrong padding in the string"}, true},
+		{&APIError{ErrorCode: 400, Description: "Bad Request: wrong file_id or the file is temporarily unavailable"}, true},
+		{&APIError{ErrorCode: 400, Description: "Bad Request: FILE_REFERENCE_EXPIRED"}, true},
+		{&APIError{ErrorCode: 400, Description: "Bad Request: FILE_ID_INVALID"}, true},
+		{&APIError{ErrorCode: 400, Description: "Bad Request: wrong file type"}, false},
+		{&APIError{ErrorCode: 400, Description: "Bad Request: file is too big"}, false},
+		{&APIError{ErrorCode: 400, Description: "Bad Request: message caption is too long"}, false},
+		{&APIError{ErrorCode: 400, Description: "Bad Request: chat not found"}, false},
+		{&APIError{ErrorCode: 429, Description: "Too Many Requests: retry after 5"}, false},
+		{&RequestError{Method: "sendPhoto", Err: errors.New("wrong file identifier")}, false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		if rejected := isFileIDRejected(test.err); rejected != test.rejected {
+			t.Errorf("%v: expected %v, but got %v", test.err, test.rejected, rejected)
+		}
+	}
+}
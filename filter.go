@@ -0,0 +1,227 @@
+package telegrambot
+
+import (
+	"context"
+	"regexp"
+)
+
+// Filter is a function type for checking if an update matches some conditions.
+//
+// Filters can be combined with And, Or, and Not, eg. `Filters.ChatType(ChatTypePrivate).And(Filters.Regex(re))`.
+type Filter func(b *Bot, update Update) bool
+
+// And returns a filter which matches when this filter and all of given filters match.
+func (f Filter) And(filters ...Filter) Filter {
+	return allFilters(append([]Filter{f}, filters...))
+}
+
+// Or returns a filter which matches when this filter or any of given filters matches.
+func (f Filter) Or(filters ...Filter) Filter {
+	filters = append([]Filter{f}, filters...)
+
+	return func(b *Bot, update Update) bool {
+		for _, filter := range filters {
+			if filter(b, update) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a filter which matches when this filter does not match.
+func (f Filter) Not() Filter {
+	return func(b *Bot, update Update) bool {
+		return !f(b, update)
+	}
+}
+
+// Returns a filter which matches when all of given filters match. (always matches if there is no filter, nil ones are ignored)
+func allFilters(filters []Filter) Filter {
+	return func(b *Bot, update Update) bool {
+		for _, filter := range filters {
+			if filter != nil && !filter(b, update) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// namespace of built-in filters
+type filters struct{}
+
+// Filters is a namespace of built-in filters.
+var Filters filters
+
+// Any returns a filter which matches all updates.
+func (filters) Any() Filter {
+	return func(b *Bot, update Update) bool {
+		return true
+	}
+}
+
+// ChatType returns a filter which matches updates from chats of given types.
+func (filters) ChatType(chatTypes ...ChatType) Filter {
+	return func(b *Bot, update Update) bool {
+		if message := updateMessage(update); message != nil {
+			for _, chatType := range chatTypes {
+				if message.Chat.Type == chatType {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// ChatID returns a filter which matches updates from chats with given ids.
+func (filters) ChatID(chatIDs ...int64) Filter {
+	return func(b *Bot, update Update) bool {
+		if message := updateMessage(update); message != nil {
+			for _, chatID := range chatIDs {
+				if message.Chat.ID == chatID {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// FromUserID returns a filter which matches updates sent from users with given ids.
+func (filters) FromUserID(userIDs ...int) Filter {
+	return func(b *Bot, update Update) bool {
+		if from := updateSender(update); from != nil {
+			for _, userID := range userIDs {
+				if from.ID == userID {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// Regex returns a filter which matches messages with text (or caption) matching given regular expression,
+// and also callback queries with data, or inline queries with query matching it.
+func (filters) Regex(re *regexp.Regexp) Filter {
+	return func(b *Bot, update Update) bool {
+		switch {
+		case update.CallbackQuery != nil:
+			return update.CallbackQuery.Data != nil && re.MatchString(*update.CallbackQuery.Data)
+		case update.InlineQuery != nil:
+			return re.MatchString(update.InlineQuery.Query)
+		}
+
+		if message := updateMessage(update); message != nil {
+			if message.HasText() {
+				return re.MatchString(*message.Text)
+			} else if message.HasCaption() {
+				return re.MatchString(*message.Caption)
+			}
+		}
+		return false
+	}
+}
+
+// Text returns a filter which matches messages with text.
+func (filters) Text() Filter {
+	return messageFilter((*Message).HasText)
+}
+
+// Photo returns a filter which matches messages with photo.
+func (filters) Photo() Filter {
+	return messageFilter((*Message).HasPhoto)
+}
+
+// Document returns a filter which matches messages with document.
+func (filters) Document() Filter {
+	return messageFilter((*Message).HasDocument)
+}
+
+// Forwarded returns a filter which matches forwarded messages.
+func (filters) Forwarded() Filter {
+	return messageFilter(func(m *Message) bool {
+		return m.ForwardDate > 0
+	})
+}
+
+// ForwardedFrom returns a filter which matches messages forwarded from users with given ids.
+func (filters) ForwardedFrom(userIDs ...int) Filter {
+	return messageFilter(func(m *Message) bool {
+		if m.HasForwardFrom() {
+			for _, userID := range userIDs {
+				if m.ForwardFrom.ID == userID {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// ReplyToBot returns a filter which matches messages replying to the messages of this bot.
+func (filters) ReplyToBot() Filter {
+	return func(b *Bot, update Update) bool {
+		if message := updateMessage(update); message != nil && message.HasReplyTo() && message.ReplyToMessage.From != nil {
+			me, err := b.getMe(context.Background())
+			if err != nil {
+				b.error("failed to get info of the bot (%s)", err)
+				return false
+			}
+			return message.ReplyToMessage.From.ID == me.ID
+		}
+		return false
+	}
+}
+
+// Returns a filter which matches messages satisfying given function.
+func messageFilter(fn func(m *Message) bool) Filter {
+	return func(b *Bot, update Update) bool {
+		if message := updateMessage(update); message != nil {
+			return fn(message)
+		}
+		return false
+	}
+}
+
+// Get the message of given update. (nil if there is none)
+func updateMessage(update Update) *Message {
+	switch {
+	case update.Message != nil:
+		return update.Message
+	case update.EditedMessage != nil:
+		return update.EditedMessage
+	case update.ChannelPost != nil:
+		return update.ChannelPost
+	case update.EditedChannelPost != nil:
+		return update.EditedChannelPost
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.Message
+	}
+
+	return nil
+}
+
+// Get the sender of given update. (nil if there is none)
+func updateSender(update Update) *User {
+	switch {
+	case update.InlineQuery != nil:
+		return &update.InlineQuery.From
+	case update.ChosenInlineResult != nil:
+		return &update.ChosenInlineResult.From
+	case update.CallbackQuery != nil:
+		return &update.CallbackQuery.From
+	case update.ShippingQuery != nil:
+		return &update.ShippingQuery.From
+	case update.PreCheckoutQuery != nil:
+		return &update.PreCheckoutQuery.From
+	}
+
+	if message := updateMessage(update); message != nil {
+		return message.From
+	}
+
+	return nil
+}
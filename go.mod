@@ -1,5 +1,5 @@
 module github.com/meinside/telegram-bot-go
 
-go 1.12
+go 1.13
 
 require github.com/meinside/wasm-helper-go v0.0.4
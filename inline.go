@@ -0,0 +1,77 @@
+package telegrambot
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	maxInlineQueryResults        = 50 // maximum number of results for an inline query
+	maxInlineQueryResultIDLength = 64 // maximum length of an inline query result's id in bytes
+)
+
+// InlineQueryResultsProvider is a function type for providing all results of given inline query.
+//
+// Results should be in a stable order, as they will be sliced into pages with offsets.
+type InlineQueryResultsProvider func(query InlineQuery) (results []interface{}, err error)
+
+// for getting ids of inline query results (InlineQueryResultArticle, InlineQueryResultPhoto, ...)
+type inlineQueryResultIdentifiable interface {
+	getID() string
+}
+
+// Get a page of results for given inline query, and the offset of the next page. (empty if it is the last page)
+func paginateInlineQueryResults(query InlineQuery, pageSize int, provider InlineQueryResultsProvider) (page []interface{}, nextOffset string, err error) {
+	if pageSize < 1 || pageSize > maxInlineQueryResults {
+		return nil, "", fmt.Errorf("page size should be between 1 and %d: %d", maxInlineQueryResults, pageSize)
+	}
+
+	offset := 0
+	if query.Offset != "" {
+		if offset, err = strconv.Atoi(query.Offset); err != nil || offset < 0 {
+			return nil, "", fmt.Errorf("invalid offset of inline query: '%s'", query.Offset)
+		}
+	}
+
+	var results []interface{}
+	if results, err = provider(query); err != nil {
+		return nil, "", fmt.Errorf("failed to provide inline query results - %w", err)
+	}
+
+	if offset >= len(results) {
+		return []interface{}{}, "", nil
+	}
+
+	end := offset + pageSize
+	if end < len(results) {
+		nextOffset = strconv.Itoa(end)
+	} else {
+		end = len(results)
+	}
+
+	return results[offset:end], nextOffset, nil
+}
+
+// Check the number of given inline query results, and the uniqueness and lengths of their ids.
+func validateInlineQueryResults(results []interface{}) error {
+	if len(results) > maxInlineQueryResults {
+		return fmt.Errorf("too many inline query results: %d (max: %d)", len(results), maxInlineQueryResults)
+	}
+
+	ids := map[string]bool{}
+	for _, result := range results {
+		if identifiable, ok := result.(inlineQueryResultIdentifiable); ok {
+			id := identifiable.getID()
+
+			if len(id) == 0 || len(id) > maxInlineQueryResultIDLength {
+				return fmt.Errorf("id of inline query result should be 1-%d bytes: '%s'", maxInlineQueryResultIDLength, id)
+			}
+			if ids[id] {
+				return fmt.Errorf("duplicated id of inline query result: '%s'", id)
+			}
+			ids[id] = true
+		}
+	}
+
+	return nil
+}
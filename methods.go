@@ -4,27 +4,41 @@ package telegrambot
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // GetUpdates retrieves updates from Telegram bot API.
 //
 // https://core.telegram.org/bots/api#getupdates
 func (b *Bot) GetUpdates(options OptionsGetUpdates) (result APIResponseUpdates) {
+	return b.GetUpdatesContext(context.Background(), options)
+}
+
+// GetUpdatesContext is the same as GetUpdates, but with given context.
+func (b *Bot) GetUpdatesContext(ctx context.Context, options OptionsGetUpdates) (result APIResponseUpdates) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
 
-	return b.requestResponseUpdates("getUpdates", options)
+	// long polling: time out a little later than the server-side timeout
+	if timeout, ok := options["timeout"].(int); ok && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second+longPollingTimeoutMargin)
+		defer cancel()
+	}
+
+	return b.requestResponseUpdates(ctx, "getUpdates", options)
 }
 
 // SetWebhookWithOptions sets webhook url, certificate, and various options for receiving incoming updates.
@@ -34,30 +48,86 @@ func (b *Bot) GetUpdates(options OptionsGetUpdates) (result APIResponseUpdates)
 //
 // https://core.telegram.org/bots/api#setwebhook
 func (b *Bot) SetWebhookWithOptions(host string, port int, certFilepath string, maxConnections int, allowedUpdates []UpdateType) (result APIResponseBool) {
+	return b.SetWebhookWithOptionsContext(context.Background(), host, port, certFilepath, maxConnections, allowedUpdates)
+}
+
+// SetWebhookWithOptionsContext is the same as SetWebhookWithOptions, but with given context.
+func (b *Bot) SetWebhookWithOptionsContext(ctx context.Context, host string, port int, certFilepath string, maxConnections int, allowedUpdates []UpdateType) (result APIResponseBool) {
+	return b.SetWebhookHostContext(ctx, host, port, OptionsSetWebhook{}.
+		SetCertificate(certFilepath).
+		SetMaxConnections(maxConnections).
+		SetAllowedUpdates(allowedUpdates))
+}
+
+// SetWebhookHost sets webhook url (with given host and port), and various options for receiving incoming updates.
+//
+// Use this function instead of SetWebhookWithOptions() for options like secret_token.
+//
+// port should be one of: 443, 80, 88, or 8443.
+//
+// https://core.telegram.org/bots/api#setwebhook
+func (b *Bot) SetWebhookHost(host string, port int, options OptionsSetWebhook) (result APIResponseBool) {
+	return b.SetWebhookHostContext(context.Background(), host, port, options)
+}
+
+// SetWebhookHostContext is the same as SetWebhookHost, but with given context.
+func (b *Bot) SetWebhookHostContext(ctx context.Context, host string, port int, options OptionsSetWebhook) (result APIResponseBool) {
 	b.webhookHost = host
 	b.webhookPort = port
-	b.webhookURL = b.getWebhookURL()
 
-	file, err := os.Open(certFilepath)
-	if err != nil {
-		panic(err)
-	}
+	return b.setWebhook(ctx, b.getWebhookURL(), options)
+}
 
-	params := map[string]interface{}{
-		"url":             b.webhookURL,
-		"certificate":     file,
-		"max_connections": maxConnections,
-		"allowed_updates": allowedUpdates,
-	}
+// SetWebhookURL sets given url as webhook url, with various options for receiving incoming updates.
+//
+// Use this function instead of SetWebhook() when the webhook is served behind a reverse proxy,
+// or with WebhookHandler() mounted on your own http server.
+//
+// https://core.telegram.org/bots/api#setwebhook
+func (b *Bot) SetWebhookURL(webhookURL string, options OptionsSetWebhook) (result APIResponseBool) {
+	return b.SetWebhookURLContext(context.Background(), webhookURL, options)
+}
 
-	b.verbose("setting webhook url to: %s", b.webhookURL)
+// SetWebhookURLContext is the same as SetWebhookURL, but with given context.
+func (b *Bot) SetWebhookURLContext(ctx context.Context, webhookURL string, options OptionsSetWebhook) (result APIResponseBool) {
+	b.webhookHost = ""
+	b.webhookPort = 0
 
-	return b.requestResponseBool("setWebhook", params)
+	return b.setWebhook(ctx, webhookURL, options)
 }
 
 // SetWebhook sets webhook url and certificate for receiving incoming updates.
 func (b *Bot) SetWebhook(host string, port int, certFilepath string) (result APIResponseBool) {
-	return b.SetWebhookWithOptions(host, port, certFilepath, 40, []UpdateType{})
+	return b.SetWebhookContext(context.Background(), host, port, certFilepath)
+}
+
+// SetWebhookContext is the same as SetWebhook, but with given context.
+func (b *Bot) SetWebhookContext(ctx context.Context, host string, port int, certFilepath string) (result APIResponseBool) {
+	return b.SetWebhookWithOptionsContext(ctx, host, port, certFilepath, 40, []UpdateType{})
+}
+
+// Set webhook url with given options.
+func (b *Bot) setWebhook(ctx context.Context, webhookURL string, options OptionsSetWebhook) (result APIResponseBool) {
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+
+	b.webhookURL = webhookURL
+
+	// essential params
+	options["url"] = webhookURL
+
+	b.verbose("setting webhook url to: %s", b.webhookURL)
+
+	result = b.requestResponseBool(ctx, "setWebhook", options)
+
+	// keep secret token for verifying incoming webhook requests (only when it was accepted by the server)
+	if result.Ok {
+		secretToken, _ := options["secret_token"].(string)
+		b.webhookSecretToken = secretToken
+	}
+
+	return result
 }
 
 // DeleteWebhook deletes webhook for this bot.
@@ -65,33 +135,54 @@ func (b *Bot) SetWebhook(host string, port int, certFilepath string) (result API
 //
 // https://core.telegram.org/bots/api#deletewebhook
 func (b *Bot) DeleteWebhook() (result APIResponseBool) {
+	return b.DeleteWebhookContext(context.Background())
+}
+
+// DeleteWebhookContext is the same as DeleteWebhook, but with given context.
+func (b *Bot) DeleteWebhookContext(ctx context.Context) (result APIResponseBool) {
 	b.webhookHost = ""
 	b.webhookPort = 0
 	b.webhookURL = ""
+	b.webhookSecretToken = ""
 
 	b.verbose("deleting webhook url")
 
-	return b.requestResponseBool("deleteWebhook", map[string]interface{}{})
+	return b.requestResponseBool(ctx, "deleteWebhook", map[string]interface{}{})
 }
 
 // GetWebhookInfo gets webhook info for this bot.
 //
 // https://core.telegram.org/bots/api#getwebhookinfo
 func (b *Bot) GetWebhookInfo() (result APIResponseWebhookInfo) {
-	return b.requestResponseWebhookInfo()
+	return b.GetWebhookInfoContext(context.Background())
+}
+
+// GetWebhookInfoContext is the same as GetWebhookInfo, but with given context.
+func (b *Bot) GetWebhookInfoContext(ctx context.Context) (result APIResponseWebhookInfo) {
+	return b.requestResponseWebhookInfo(ctx)
 }
 
 // GetMe gets info of this bot.
 //
 // https://core.telegram.org/bots/api#getme
 func (b *Bot) GetMe() (result APIResponseUser) {
-	return b.requestResponseUser("getMe", map[string]interface{}{}) // no params
+	return b.GetMeContext(context.Background())
+}
+
+// GetMeContext is the same as GetMe, but with given context.
+func (b *Bot) GetMeContext(ctx context.Context) (result APIResponseUser) {
+	return b.requestResponseUser(ctx, "getMe", map[string]interface{}{}) // no params
 }
 
 // SendMessage sends a message to the bot.
 //
 // https://core.telegram.org/bots/api#sendmessage
 func (b *Bot) SendMessage(chatID ChatID, text string, options OptionsSendMessage) (result APIResponseMessage) {
+	return b.SendMessageContext(context.Background(), chatID, text, options)
+}
+
+// SendMessageContext is the same as SendMessage, but with given context.
+func (b *Bot) SendMessageContext(ctx context.Context, chatID ChatID, text string, options OptionsSendMessage) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -100,13 +191,18 @@ func (b *Bot) SendMessage(chatID ChatID, text string, options OptionsSendMessage
 	options["chat_id"] = chatID
 	options["text"] = text
 
-	return b.requestResponseMessage("sendMessage", options)
+	return b.requestResponseMessage(ctx, "sendMessage", options)
 }
 
 // ForwardMessage forwards a message.
 //
 // https://core.telegram.org/bots/api#forwardmessage
 func (b *Bot) ForwardMessage(chatID, fromChatID ChatID, messageID int, options OptionsForwardMessage) (result APIResponseMessage) {
+	return b.ForwardMessageContext(context.Background(), chatID, fromChatID, messageID, options)
+}
+
+// ForwardMessageContext is the same as ForwardMessage, but with given context.
+func (b *Bot) ForwardMessageContext(ctx context.Context, chatID, fromChatID ChatID, messageID int, options OptionsForwardMessage) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -116,13 +212,18 @@ func (b *Bot) ForwardMessage(chatID, fromChatID ChatID, messageID int, options O
 	options["from_chat_id"] = fromChatID
 	options["message_id"] = messageID
 
-	return b.requestResponseMessage("forwardMessage", options)
+	return b.requestResponseMessage(ctx, "forwardMessage", options)
 }
 
 // SendPhoto sends a photo.
 //
 // https://core.telegram.org/bots/api#sendphoto
 func (b *Bot) SendPhoto(chatID ChatID, photo InputFile, options OptionsSendPhoto) (result APIResponseMessage) {
+	return b.SendPhotoContext(context.Background(), chatID, photo, options)
+}
+
+// SendPhotoContext is the same as SendPhoto, but with given context.
+func (b *Bot) SendPhotoContext(ctx context.Context, chatID ChatID, photo InputFile, options OptionsSendPhoto) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -131,13 +232,18 @@ func (b *Bot) SendPhoto(chatID ChatID, photo InputFile, options OptionsSendPhoto
 	options["chat_id"] = chatID
 	options["photo"] = photo
 
-	return b.requestResponseMessage("sendPhoto", options)
+	return b.requestResponseMessage(ctx, "sendPhoto", options)
 }
 
 // SendAudio sends an audio file. (.mp3 format only, will be played with external players)
 //
 // https://core.telegram.org/bots/api#sendaudio
 func (b *Bot) SendAudio(chatID ChatID, audio InputFile, options OptionsSendAudio) (result APIResponseMessage) {
+	return b.SendAudioContext(context.Background(), chatID, audio, options)
+}
+
+// SendAudioContext is the same as SendAudio, but with given context.
+func (b *Bot) SendAudioContext(ctx context.Context, chatID ChatID, audio InputFile, options OptionsSendAudio) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -146,13 +252,18 @@ func (b *Bot) SendAudio(chatID ChatID, audio InputFile, options OptionsSendAudio
 	options["chat_id"] = chatID
 	options["audio"] = audio
 
-	return b.requestResponseMessage("sendAudio", options)
+	return b.requestResponseMessage(ctx, "sendAudio", options)
 }
 
 // SendDocument sends a general file.
 //
 // https://core.telegram.org/bots/api#senddocument
 func (b *Bot) SendDocument(chatID ChatID, document InputFile, options OptionsSendDocument) (result APIResponseMessage) {
+	return b.SendDocumentContext(context.Background(), chatID, document, options)
+}
+
+// SendDocumentContext is the same as SendDocument, but with given context.
+func (b *Bot) SendDocumentContext(ctx context.Context, chatID ChatID, document InputFile, options OptionsSendDocument) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -161,13 +272,18 @@ func (b *Bot) SendDocument(chatID ChatID, document InputFile, options OptionsSen
 	options["chat_id"] = chatID
 	options["document"] = document
 
-	return b.requestResponseMessage("sendDocument", options)
+	return b.requestResponseMessage(ctx, "sendDocument", options)
 }
 
 // SendSticker sends a sticker.
 //
 // https://core.telegram.org/bots/api#sendsticker
 func (b *Bot) SendSticker(chatID ChatID, sticker InputFile, options OptionsSendSticker) (result APIResponseMessage) {
+	return b.SendStickerContext(context.Background(), chatID, sticker, options)
+}
+
+// SendStickerContext is the same as SendSticker, but with given context.
+func (b *Bot) SendStickerContext(ctx context.Context, chatID ChatID, sticker InputFile, options OptionsSendSticker) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -176,38 +292,53 @@ func (b *Bot) SendSticker(chatID ChatID, sticker InputFile, options OptionsSendS
 	options["chat_id"] = chatID
 	options["sticker"] = sticker
 
-	return b.requestResponseMessage("sendSticker", options)
+	return b.requestResponseMessage(ctx, "sendSticker", options)
 }
 
 // GetStickerSet gets a sticker set.
 //
 // https://core.telegram.org/bots/api#getstickerset
 func (b *Bot) GetStickerSet(name string) (result APIResponseStickerSet) {
+	return b.GetStickerSetContext(context.Background(), name)
+}
+
+// GetStickerSetContext is the same as GetStickerSet, but with given context.
+func (b *Bot) GetStickerSetContext(ctx context.Context, name string) (result APIResponseStickerSet) {
 	// essential params
 	params := map[string]interface{}{
 		"name": name,
 	}
 
-	return b.requestResponseStickerSet("getStickerSet", params)
+	return b.requestResponseStickerSet(ctx, "getStickerSet", params)
 }
 
 // UploadStickerFile uploads a sticker file.
 //
 // https://core.telegram.org/bots/api#uploadstickerfile
 func (b *Bot) UploadStickerFile(userID int, sticker InputFile) (result APIResponseFile) {
+	return b.UploadStickerFileContext(context.Background(), userID, sticker)
+}
+
+// UploadStickerFileContext is the same as UploadStickerFile, but with given context.
+func (b *Bot) UploadStickerFileContext(ctx context.Context, userID int, sticker InputFile) (result APIResponseFile) {
 	// essential params
 	params := map[string]interface{}{
 		"user_id":     userID,
 		"png_sticker": sticker,
 	}
 
-	return b.requestResponseFile("uploadStickerFile", params)
+	return b.requestResponseFile(ctx, "uploadStickerFile", params)
 }
 
 // CreateNewStickerSet creates a new sticker set.
 //
 // https://core.telegram.org/bots/api#createnewstickerset
 func (b *Bot) CreateNewStickerSet(userID int, name, title string, sticker InputFile, emojis string, options OptionsCreateNewStickerSet) (result APIResponseBool) {
+	return b.CreateNewStickerSetContext(context.Background(), userID, name, title, sticker, emojis, options)
+}
+
+// CreateNewStickerSetContext is the same as CreateNewStickerSet, but with given context.
+func (b *Bot) CreateNewStickerSetContext(ctx context.Context, userID int, name, title string, sticker InputFile, emojis string, options OptionsCreateNewStickerSet) (result APIResponseBool) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -219,13 +350,18 @@ func (b *Bot) CreateNewStickerSet(userID int, name, title string, sticker InputF
 	options["emojis"] = emojis
 	options["png_sticker"] = sticker
 
-	return b.requestResponseBool("createNewStickerSet", options)
+	return b.requestResponseBool(ctx, "createNewStickerSet", options)
 }
 
 // AddStickerToSet adds a sticker to set.
 //
 // https://core.telegram.org/bots/api#addstickertoset
 func (b *Bot) AddStickerToSet(userID int, name string, sticker InputFile, emojis string, options OptionsAddStickerToSet) (result APIResponseBool) {
+	return b.AddStickerToSetContext(context.Background(), userID, name, sticker, emojis, options)
+}
+
+// AddStickerToSetContext is the same as AddStickerToSet, but with given context.
+func (b *Bot) AddStickerToSetContext(ctx context.Context, userID int, name string, sticker InputFile, emojis string, options OptionsAddStickerToSet) (result APIResponseBool) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -236,38 +372,53 @@ func (b *Bot) AddStickerToSet(userID int, name string, sticker InputFile, emojis
 	options["emojis"] = emojis
 	options["png_sticker"] = sticker
 
-	return b.requestResponseBool("addStickerToSet", options)
+	return b.requestResponseBool(ctx, "addStickerToSet", options)
 }
 
 // SetStickerPositionInSet sets sticker position in set.
 //
 // https://core.telegram.org/bots/api#setstickerpositioninset
 func (b *Bot) SetStickerPositionInSet(sticker string, position int) (result APIResponseBool) {
+	return b.SetStickerPositionInSetContext(context.Background(), sticker, position)
+}
+
+// SetStickerPositionInSetContext is the same as SetStickerPositionInSet, but with given context.
+func (b *Bot) SetStickerPositionInSetContext(ctx context.Context, sticker string, position int) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"sticker":  sticker,
 		"position": position,
 	}
 
-	return b.requestResponseBool("setStickerPositionInSet", params)
+	return b.requestResponseBool(ctx, "setStickerPositionInSet", params)
 }
 
 // DeleteStickerFromSet deletes a sticker from set.
 //
 // https://core.telegram.org/bots/api#deletestickerfromset
 func (b *Bot) DeleteStickerFromSet(sticker string) (result APIResponseBool) {
+	return b.DeleteStickerFromSetContext(context.Background(), sticker)
+}
+
+// DeleteStickerFromSetContext is the same as DeleteStickerFromSet, but with given context.
+func (b *Bot) DeleteStickerFromSetContext(ctx context.Context, sticker string) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"sticker": sticker,
 	}
 
-	return b.requestResponseBool("deleteStickerFromSet", params)
+	return b.requestResponseBool(ctx, "deleteStickerFromSet", params)
 }
 
 // SendVideo sends a video file.
 //
 // https://core.telegram.org/bots/api#sendvideo
 func (b *Bot) SendVideo(chatID ChatID, video InputFile, options OptionsSendVideo) (result APIResponseMessage) {
+	return b.SendVideoContext(context.Background(), chatID, video, options)
+}
+
+// SendVideoContext is the same as SendVideo, but with given context.
+func (b *Bot) SendVideoContext(ctx context.Context, chatID ChatID, video InputFile, options OptionsSendVideo) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -276,13 +427,18 @@ func (b *Bot) SendVideo(chatID ChatID, video InputFile, options OptionsSendVideo
 	options["chat_id"] = chatID
 	options["video"] = video
 
-	return b.requestResponseMessage("sendVideo", options)
+	return b.requestResponseMessage(ctx, "sendVideo", options)
 }
 
 // SendAnimation sends an animation.
 //
 // https://core.telegram.org/bots/api#sendanimation
 func (b *Bot) SendAnimation(chatID ChatID, animation InputFile, options OptionsSendAnimation) (result APIResponseMessage) {
+	return b.SendAnimationContext(context.Background(), chatID, animation, options)
+}
+
+// SendAnimationContext is the same as SendAnimation, but with given context.
+func (b *Bot) SendAnimationContext(ctx context.Context, chatID ChatID, animation InputFile, options OptionsSendAnimation) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -291,13 +447,18 @@ func (b *Bot) SendAnimation(chatID ChatID, animation InputFile, options OptionsS
 	options["chat_id"] = chatID
 	options["animation"] = animation
 
-	return b.requestResponseMessage("sendAnimation", options)
+	return b.requestResponseMessage(ctx, "sendAnimation", options)
 }
 
 // SendVoice sends a voice file. (.ogg format only, will be played with Telegram itself))
 //
 // https://core.telegram.org/bots/api#sendvoice
 func (b *Bot) SendVoice(chatID ChatID, voice InputFile, options OptionsSendVoice) (result APIResponseMessage) {
+	return b.SendVoiceContext(context.Background(), chatID, voice, options)
+}
+
+// SendVoiceContext is the same as SendVoice, but with given context.
+func (b *Bot) SendVoiceContext(ctx context.Context, chatID ChatID, voice InputFile, options OptionsSendVoice) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -306,7 +467,7 @@ func (b *Bot) SendVoice(chatID ChatID, voice InputFile, options OptionsSendVoice
 	options["chat_id"] = chatID
 	options["voice"] = voice
 
-	return b.requestResponseMessage("sendVoice", options)
+	return b.requestResponseMessage(ctx, "sendVoice", options)
 }
 
 // SendVideoNote sends a video note.
@@ -315,6 +476,11 @@ func (b *Bot) SendVoice(chatID ChatID, voice InputFile, options OptionsSendVoice
 //
 // https://core.telegram.org/bots/api#sendvideonote
 func (b *Bot) SendVideoNote(chatID ChatID, videoNote InputFile, options OptionsSendVideoNote) (result APIResponseMessage) {
+	return b.SendVideoNoteContext(context.Background(), chatID, videoNote, options)
+}
+
+// SendVideoNoteContext is the same as SendVideoNote, but with given context.
+func (b *Bot) SendVideoNoteContext(ctx context.Context, chatID ChatID, videoNote InputFile, options OptionsSendVideoNote) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -323,28 +489,38 @@ func (b *Bot) SendVideoNote(chatID ChatID, videoNote InputFile, options OptionsS
 	options["chat_id"] = chatID
 	options["video_note"] = videoNote
 
-	return b.requestResponseMessage("sendVideoNote", options)
+	return b.requestResponseMessage(ctx, "sendVideoNote", options)
 }
 
 // SendMediaGroup sends a group of photos or videos as an album.
 //
 // https://core.telegram.org/bots/api#sendmediagroup
 func (b *Bot) SendMediaGroup(chatID ChatID, media []InputMedia, options OptionsSendMediaGroup) (result APIResponseMessages) {
+	return b.SendMediaGroupContext(context.Background(), chatID, media, options)
+}
+
+// SendMediaGroupContext is the same as SendMediaGroup, but with given context.
+func (b *Bot) SendMediaGroupContext(ctx context.Context, chatID ChatID, media []InputMedia, options OptionsSendMediaGroup) (result APIResponseMessages) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
 
 	// essential params
 	options["chat_id"] = chatID
-	options["media"] = media
+	options["media"] = attachInputMedia(options, media...)
 
-	return b.requestResponseMessages("sendMediaGroup", options)
+	return b.requestResponseMessages(ctx, "sendMediaGroup", options)
 }
 
 // SendLocation sends locations.
 //
 // https://core.telegram.org/bots/api#sendlocation
 func (b *Bot) SendLocation(chatID ChatID, latitude, longitude float32, options OptionsSendLocation) (result APIResponseMessage) {
+	return b.SendLocationContext(context.Background(), chatID, latitude, longitude, options)
+}
+
+// SendLocationContext is the same as SendLocation, but with given context.
+func (b *Bot) SendLocationContext(ctx context.Context, chatID ChatID, latitude, longitude float32, options OptionsSendLocation) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -354,13 +530,18 @@ func (b *Bot) SendLocation(chatID ChatID, latitude, longitude float32, options O
 	options["latitude"] = latitude
 	options["longitude"] = longitude
 
-	return b.requestResponseMessage("sendLocation", options)
+	return b.requestResponseMessage(ctx, "sendLocation", options)
 }
 
 // SendVenue sends venues.
 //
 // https://core.telegram.org/bots/api#sendvenue
 func (b *Bot) SendVenue(chatID ChatID, latitude, longitude float32, title, address string, options OptionsSendVenue) (result APIResponseMessage) {
+	return b.SendVenueContext(context.Background(), chatID, latitude, longitude, title, address, options)
+}
+
+// SendVenueContext is the same as SendVenue, but with given context.
+func (b *Bot) SendVenueContext(ctx context.Context, chatID ChatID, latitude, longitude float32, title, address string, options OptionsSendVenue) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -372,13 +553,18 @@ func (b *Bot) SendVenue(chatID ChatID, latitude, longitude float32, title, addre
 	options["title"] = title
 	options["address"] = address
 
-	return b.requestResponseMessage("sendVenue", options)
+	return b.requestResponseMessage(ctx, "sendVenue", options)
 }
 
 // SendContact sends contacts.
 //
 // https://core.telegram.org/bots/api#sendcontact
 func (b *Bot) SendContact(chatID ChatID, phoneNumber, firstName string, options OptionsSendContact) (result APIResponseMessage) {
+	return b.SendContactContext(context.Background(), chatID, phoneNumber, firstName, options)
+}
+
+// SendContactContext is the same as SendContact, but with given context.
+func (b *Bot) SendContactContext(ctx context.Context, chatID ChatID, phoneNumber, firstName string, options OptionsSendContact) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -388,13 +574,18 @@ func (b *Bot) SendContact(chatID ChatID, phoneNumber, firstName string, options
 	options["phone_number"] = phoneNumber
 	options["first_name"] = firstName
 
-	return b.requestResponseMessage("sendContact", options)
+	return b.requestResponseMessage(ctx, "sendContact", options)
 }
 
 // SendPoll sends a poll.
 //
 // https://core.telegram.org/bots/api#sendpoll
 func (b *Bot) SendPoll(chatID ChatID, question string, pollOptions []string, options OptionsSendPoll) (result APIResponseMessage) {
+	return b.SendPollContext(context.Background(), chatID, question, pollOptions, options)
+}
+
+// SendPollContext is the same as SendPoll, but with given context.
+func (b *Bot) SendPollContext(ctx context.Context, chatID ChatID, question string, pollOptions []string, options OptionsSendPoll) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -404,13 +595,18 @@ func (b *Bot) SendPoll(chatID ChatID, question string, pollOptions []string, opt
 	options["question"] = question
 	options["options"] = pollOptions
 
-	return b.requestResponseMessage("sendPoll", options)
+	return b.requestResponseMessage(ctx, "sendPoll", options)
 }
 
 // StopPoll stops a poll.
 //
 // https://core.telegram.org/bots/api#stoppoll
 func (b *Bot) StopPoll(chatID ChatID, messageID int, options OptionsStopPoll) (result APIResponsePoll) {
+	return b.StopPollContext(context.Background(), chatID, messageID, options)
+}
+
+// StopPollContext is the same as StopPoll, but with given context.
+func (b *Bot) StopPollContext(ctx context.Context, chatID ChatID, messageID int, options OptionsStopPoll) (result APIResponsePoll) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -419,26 +615,36 @@ func (b *Bot) StopPoll(chatID ChatID, messageID int, options OptionsStopPoll) (r
 	options["chat_id"] = chatID
 	options["message_id"] = messageID
 
-	return b.requestResponsePoll("stopPoll", options)
+	return b.requestResponsePoll(ctx, "stopPoll", options)
 }
 
 // SendChatAction sends chat actions.
 //
 // https://core.telegram.org/bots/api#sendchataction
 func (b *Bot) SendChatAction(chatID ChatID, action ChatAction) (result APIResponseBool) {
+	return b.SendChatActionContext(context.Background(), chatID, action)
+}
+
+// SendChatActionContext is the same as SendChatAction, but with given context.
+func (b *Bot) SendChatActionContext(ctx context.Context, chatID ChatID, action ChatAction) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 		"action":  action,
 	}
 
-	return b.requestResponseBool("sendChatAction", params)
+	return b.requestResponseBool(ctx, "sendChatAction", params)
 }
 
 // GetUserProfilePhotos gets user profile photos.
 //
 // https://core.telegram.org/bots/api#getuserprofilephotos
 func (b *Bot) GetUserProfilePhotos(userID int, options OptionsGetUserProfilePhotos) (result APIResponseUserProfilePhotos) {
+	return b.GetUserProfilePhotosContext(context.Background(), userID, options)
+}
+
+// GetUserProfilePhotosContext is the same as GetUserProfilePhotos, but with given context.
+func (b *Bot) GetUserProfilePhotosContext(ctx context.Context, userID int, options OptionsGetUserProfilePhotos) (result APIResponseUserProfilePhotos) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -446,41 +652,62 @@ func (b *Bot) GetUserProfilePhotos(userID int, options OptionsGetUserProfilePhot
 	// essential params
 	options["user_id"] = userID
 
-	return b.requestResponseUserProfilePhotos("getUserProfilePhotos", options)
+	return b.requestResponseUserProfilePhotos(ctx, "getUserProfilePhotos", options)
 }
 
 // GetFile gets file info and prepare for download.
 //
 // https://core.telegram.org/bots/api#getfile
 func (b *Bot) GetFile(fileID string) (result APIResponseFile) {
+	return b.GetFileContext(context.Background(), fileID)
+}
+
+// GetFileContext is the same as GetFile, but with given context.
+func (b *Bot) GetFileContext(ctx context.Context, fileID string) (result APIResponseFile) {
 	// essential params
 	params := map[string]interface{}{
 		"file_id": fileID,
 	}
 
-	return b.requestResponseFile("getFile", params)
+	return b.requestResponseFile(ctx, "getFile", params)
 }
 
 // GetFileURL gets download link from a given File.
+//
+// If the bot API server is running in local mode, File.FilePath (an absolute path on local disk) will be returned as it is.
 func (b *Bot) GetFileURL(file File) string {
-	return fmt.Sprintf("%s%s/%s", fileBaseURL, b.token, *file.FilePath)
+	if b.localMode {
+		return *file.FilePath
+	}
+
+	return fmt.Sprintf("%s/file/bot%s/%s", b.fileBaseURL, b.token, *file.FilePath)
 }
 
 // KickChatMember kicks a chat member
 //
 // https://core.telegram.org/bots/api#kickchatmember
 func (b *Bot) KickChatMember(chatID ChatID, userID int) (result APIResponseBool) {
+	return b.KickChatMemberContext(context.Background(), chatID, userID)
+}
+
+// KickChatMemberContext is the same as KickChatMember, but with given context.
+func (b *Bot) KickChatMemberContext(ctx context.Context, chatID ChatID, userID int) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 		"user_id": userID,
 	}
 
-	return b.requestResponseBool("kickChatMember", params)
+	return b.requestResponseBool(ctx, "kickChatMember", params)
 }
 
 // KickChatMemberUntil kicks a chat member until given date
 func (b *Bot) KickChatMemberUntil(chatID ChatID, userID int, untilDate int) (result APIResponseBool) {
+	return b.KickChatMemberUntilContext(context.Background(), chatID, userID, untilDate)
+}
+
+// KickChatMemberUntilContext is the same as KickChatMemberUntil, but with given context.
+func (b *Bot) KickChatMemberUntilContext(ctx context.Context, chatID ChatID, userID int, untilDate int) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id":    chatID,
@@ -488,38 +715,53 @@ func (b *Bot) KickChatMemberUntil(chatID ChatID, userID int, untilDate int) (res
 		"until_date": untilDate,
 	}
 
-	return b.requestResponseBool("kickChatMember", params)
+	return b.requestResponseBool(ctx, "kickChatMember", params)
 }
 
 // LeaveChat leaves a chat
 //
 // https://core.telegram.org/bots/api#leavechat
 func (b *Bot) LeaveChat(chatID ChatID) (result APIResponseBool) {
+	return b.LeaveChatContext(context.Background(), chatID)
+}
+
+// LeaveChatContext is the same as LeaveChat, but with given context.
+func (b *Bot) LeaveChatContext(ctx context.Context, chatID ChatID) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 	}
 
-	return b.requestResponseBool("leaveChat", params)
+	return b.requestResponseBool(ctx, "leaveChat", params)
 }
 
 // UnbanChatMember unbans a chat member
 //
 // https://core.telegram.org/bots/api#unbanchatmember
 func (b *Bot) UnbanChatMember(chatID ChatID, userID int) (result APIResponseBool) {
+	return b.UnbanChatMemberContext(context.Background(), chatID, userID)
+}
+
+// UnbanChatMemberContext is the same as UnbanChatMember, but with given context.
+func (b *Bot) UnbanChatMemberContext(ctx context.Context, chatID ChatID, userID int) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 		"user_id": userID,
 	}
 
-	return b.requestResponseBool("unbanChatMember", params)
+	return b.requestResponseBool(ctx, "unbanChatMember", params)
 }
 
 // RestrictChatMember restricts a chat member
 //
 // https://core.telegram.org/bots/api#restrictchatmember
 func (b *Bot) RestrictChatMember(chatID ChatID, userID int, options OptionsRestrictChatMember) (result APIResponseBool) {
+	return b.RestrictChatMemberContext(context.Background(), chatID, userID, options)
+}
+
+// RestrictChatMemberContext is the same as RestrictChatMember, but with given context.
+func (b *Bot) RestrictChatMemberContext(ctx context.Context, chatID ChatID, userID int, options OptionsRestrictChatMember) (result APIResponseBool) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -528,13 +770,18 @@ func (b *Bot) RestrictChatMember(chatID ChatID, userID int, options OptionsRestr
 	options["chat_id"] = chatID
 	options["user_id"] = userID
 
-	return b.requestResponseBool("restrictChatMember", options)
+	return b.requestResponseBool(ctx, "restrictChatMember", options)
 }
 
 // PromoteChatMember promotes a chat member
 //
 // https://core.telegram.org/bots/api#promotechatmember
 func (b *Bot) PromoteChatMember(chatID ChatID, userID int, options OptionsPromoteChatMember) (result APIResponseBool) {
+	return b.PromoteChatMemberContext(context.Background(), chatID, userID, options)
+}
+
+// PromoteChatMemberContext is the same as PromoteChatMember, but with given context.
+func (b *Bot) PromoteChatMemberContext(ctx context.Context, chatID ChatID, userID int, options OptionsPromoteChatMember) (result APIResponseBool) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -543,76 +790,106 @@ func (b *Bot) PromoteChatMember(chatID ChatID, userID int, options OptionsPromot
 	options["chat_id"] = chatID
 	options["user_id"] = userID
 
-	return b.requestResponseBool("promoteChatMember", options)
+	return b.requestResponseBool(ctx, "promoteChatMember", options)
 }
 
 // ExportChatInviteLink exports a chat invite link
 //
 // https://core.telegram.org/bots/api#exportchatinvitelink
 func (b *Bot) ExportChatInviteLink(chatID ChatID) (result APIResponseString) {
+	return b.ExportChatInviteLinkContext(context.Background(), chatID)
+}
+
+// ExportChatInviteLinkContext is the same as ExportChatInviteLink, but with given context.
+func (b *Bot) ExportChatInviteLinkContext(ctx context.Context, chatID ChatID) (result APIResponseString) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 	}
 
-	return b.requestResponseString("exportChatInviteLink", params)
+	return b.requestResponseString(ctx, "exportChatInviteLink", params)
 }
 
 // SetChatPhoto sets a chat photo
 //
 // https://core.telegram.org/bots/api#setchatphoto
 func (b *Bot) SetChatPhoto(chatID ChatID, photo InputFile) (result APIResponseBool) {
+	return b.SetChatPhotoContext(context.Background(), chatID, photo)
+}
+
+// SetChatPhotoContext is the same as SetChatPhoto, but with given context.
+func (b *Bot) SetChatPhotoContext(ctx context.Context, chatID ChatID, photo InputFile) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 		"photo":   photo,
 	}
 
-	return b.requestResponseBool("setChatPhoto", params)
+	return b.requestResponseBool(ctx, "setChatPhoto", params)
 }
 
 // DeleteChatPhoto deletes a chat photo
 //
 // https://core.telegram.org/bots/api#deletechatphoto
 func (b *Bot) DeleteChatPhoto(chatID ChatID) (result APIResponseBool) {
+	return b.DeleteChatPhotoContext(context.Background(), chatID)
+}
+
+// DeleteChatPhotoContext is the same as DeleteChatPhoto, but with given context.
+func (b *Bot) DeleteChatPhotoContext(ctx context.Context, chatID ChatID) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 	}
 
-	return b.requestResponseBool("deleteChatPhoto", params)
+	return b.requestResponseBool(ctx, "deleteChatPhoto", params)
 }
 
 // SetChatTitle sets a chat title
 //
 // https://core.telegram.org/bots/api#setchattitle
 func (b *Bot) SetChatTitle(chatID ChatID, title string) (result APIResponseBool) {
+	return b.SetChatTitleContext(context.Background(), chatID, title)
+}
+
+// SetChatTitleContext is the same as SetChatTitle, but with given context.
+func (b *Bot) SetChatTitleContext(ctx context.Context, chatID ChatID, title string) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 		"title":   title,
 	}
 
-	return b.requestResponseBool("setChatTitle", params)
+	return b.requestResponseBool(ctx, "setChatTitle", params)
 }
 
 // SetChatDescription sets a chat description
 //
 // https://core.telegram.org/bots/api#setchatdescription
 func (b *Bot) SetChatDescription(chatID ChatID, description string) (result APIResponseBool) {
+	return b.SetChatDescriptionContext(context.Background(), chatID, description)
+}
+
+// SetChatDescriptionContext is the same as SetChatDescription, but with given context.
+func (b *Bot) SetChatDescriptionContext(ctx context.Context, chatID ChatID, description string) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id":     chatID,
 		"description": description,
 	}
 
-	return b.requestResponseBool("setChatDescription", params)
+	return b.requestResponseBool(ctx, "setChatDescription", params)
 }
 
 // PinChatMessage pins a chat message
 //
 // https://core.telegram.org/bots/api#pinchatmessage
 func (b *Bot) PinChatMessage(chatID ChatID, messageID int, options OptionsPinChatMessage) (result APIResponseBool) {
+	return b.PinChatMessageContext(context.Background(), chatID, messageID, options)
+}
+
+// PinChatMessageContext is the same as PinChatMessage, but with given context.
+func (b *Bot) PinChatMessageContext(ctx context.Context, chatID ChatID, messageID int, options OptionsPinChatMessage) (result APIResponseBool) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -621,99 +898,139 @@ func (b *Bot) PinChatMessage(chatID ChatID, messageID int, options OptionsPinCha
 	options["chat_id"] = chatID
 	options["message_id"] = messageID
 
-	return b.requestResponseBool("pinChatMessage", options)
+	return b.requestResponseBool(ctx, "pinChatMessage", options)
 }
 
 // UnpinChatMessage unpins a chat message
 //
 // https://core.telegram.org/bots/api#unpinchatmessage
 func (b *Bot) UnpinChatMessage(chatID ChatID) (result APIResponseBool) {
+	return b.UnpinChatMessageContext(context.Background(), chatID)
+}
+
+// UnpinChatMessageContext is the same as UnpinChatMessage, but with given context.
+func (b *Bot) UnpinChatMessageContext(ctx context.Context, chatID ChatID) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 	}
 
-	return b.requestResponseBool("unpinChatMessage", params)
+	return b.requestResponseBool(ctx, "unpinChatMessage", params)
 }
 
 // GetChat gets a chat
 //
 // https://core.telegram.org/bots/api#getchat
 func (b *Bot) GetChat(chatID ChatID) (result APIResponseChat) {
+	return b.GetChatContext(context.Background(), chatID)
+}
+
+// GetChatContext is the same as GetChat, but with given context.
+func (b *Bot) GetChatContext(ctx context.Context, chatID ChatID) (result APIResponseChat) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 	}
 
-	return b.requestResponseChat("getChat", params)
+	return b.requestResponseChat(ctx, "getChat", params)
 }
 
 // GetChatAdministrators gets chat administrators
 //
 // https://core.telegram.org/bots/api#getchatadministrators
 func (b *Bot) GetChatAdministrators(chatID ChatID) (result APIResponseChatAdministrators) {
+	return b.GetChatAdministratorsContext(context.Background(), chatID)
+}
+
+// GetChatAdministratorsContext is the same as GetChatAdministrators, but with given context.
+func (b *Bot) GetChatAdministratorsContext(ctx context.Context, chatID ChatID) (result APIResponseChatAdministrators) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 	}
 
-	return b.requestResponseChatAdministrators("getChatAdministrators", params)
+	return b.requestResponseChatAdministrators(ctx, "getChatAdministrators", params)
 }
 
 // GetChatMembersCount gets chat members' count
 //
 // https://core.telegram.org/bots/api#getchatmemberscount
 func (b *Bot) GetChatMembersCount(chatID ChatID) (result APIResponseInt) {
+	return b.GetChatMembersCountContext(context.Background(), chatID)
+}
+
+// GetChatMembersCountContext is the same as GetChatMembersCount, but with given context.
+func (b *Bot) GetChatMembersCountContext(ctx context.Context, chatID ChatID) (result APIResponseInt) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 	}
 
-	return b.requestResponseInt("getChatMembersCount", params)
+	return b.requestResponseInt(ctx, "getChatMembersCount", params)
 }
 
 // GetChatMember gets a chat member
 //
 // https://core.telegram.org/bots/api#getchatmember
 func (b *Bot) GetChatMember(chatID ChatID, userID int) (result APIResponseChatMember) {
+	return b.GetChatMemberContext(context.Background(), chatID, userID)
+}
+
+// GetChatMemberContext is the same as GetChatMember, but with given context.
+func (b *Bot) GetChatMemberContext(ctx context.Context, chatID ChatID, userID int) (result APIResponseChatMember) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 		"user_id": userID,
 	}
 
-	return b.requestResponseChatMember("getChatMember", params)
+	return b.requestResponseChatMember(ctx, "getChatMember", params)
 }
 
 // SetChatStickerSet sets a chat sticker set
 //
 // https://core.telegram.org/bots/api#setchatstickerset
 func (b *Bot) SetChatStickerSet(chatID ChatID, stickerSetName string) (result APIResponseBool) {
+	return b.SetChatStickerSetContext(context.Background(), chatID, stickerSetName)
+}
+
+// SetChatStickerSetContext is the same as SetChatStickerSet, but with given context.
+func (b *Bot) SetChatStickerSetContext(ctx context.Context, chatID ChatID, stickerSetName string) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id":          chatID,
 		"sticker_set_name": stickerSetName,
 	}
 
-	return b.requestResponseBool("setChatStickerSet", params)
+	return b.requestResponseBool(ctx, "setChatStickerSet", params)
 }
 
 // DeleteChatStickerSet deletes a chat sticker set
 //
 // https://core.telegram.org/bots/api#deletechatstickerset
 func (b *Bot) DeleteChatStickerSet(chatID ChatID) (result APIResponseBool) {
+	return b.DeleteChatStickerSetContext(context.Background(), chatID)
+}
+
+// DeleteChatStickerSetContext is the same as DeleteChatStickerSet, but with given context.
+func (b *Bot) DeleteChatStickerSetContext(ctx context.Context, chatID ChatID) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"chat_id": chatID,
 	}
 
-	return b.requestResponseBool("deleteChatStickerSet", params)
+	return b.requestResponseBool(ctx, "deleteChatStickerSet", params)
 }
 
 // AnswerCallbackQuery answers a callback query
 //
 // https://core.telegram.org/bots/api#answercallbackquery
 func (b *Bot) AnswerCallbackQuery(callbackQueryID string, options OptionsAnswerCallbackQuery) (result APIResponseBool) {
+	return b.AnswerCallbackQueryContext(context.Background(), callbackQueryID, options)
+}
+
+// AnswerCallbackQueryContext is the same as AnswerCallbackQuery, but with given context.
+func (b *Bot) AnswerCallbackQueryContext(ctx context.Context, callbackQueryID string, options OptionsAnswerCallbackQuery) (result APIResponseBool) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -721,7 +1038,12 @@ func (b *Bot) AnswerCallbackQuery(callbackQueryID string, options OptionsAnswerC
 	// essential params
 	options["callback_query_id"] = callbackQueryID
 
-	return b.requestResponseBool("answerCallbackQuery", options)
+	result = b.requestResponseBool(ctx, "answerCallbackQuery", options)
+	if result.Ok {
+		b.markCallbackQueryAnswered(callbackQueryID)
+	}
+
+	return result
 }
 
 // Updating messages
@@ -732,6 +1054,11 @@ func (b *Bot) AnswerCallbackQuery(callbackQueryID string, options OptionsAnswerC
 //
 // https://core.telegram.org/bots/api#editmessagetext
 func (b *Bot) EditMessageText(text string, options OptionsEditMessageText) (result APIResponseMessageOrBool) {
+	return b.EditMessageTextContext(context.Background(), text, options)
+}
+
+// EditMessageTextContext is the same as EditMessageText, but with given context.
+func (b *Bot) EditMessageTextContext(ctx context.Context, text string, options OptionsEditMessageText) (result APIResponseMessageOrBool) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -739,13 +1066,18 @@ func (b *Bot) EditMessageText(text string, options OptionsEditMessageText) (resu
 	// essential params
 	options["text"] = text
 
-	return b.requestResponseMessageOrBool("editMessageText", options)
+	return b.requestResponseMessageOrBool(ctx, "editMessageText", options)
 }
 
 // EditMessageCaption edits caption of a message
 //
 // https://core.telegram.org/bots/api#editmessagecaption
 func (b *Bot) EditMessageCaption(caption string, options OptionsEditMessageCaption) (result APIResponseMessageOrBool) {
+	return b.EditMessageCaptionContext(context.Background(), caption, options)
+}
+
+// EditMessageCaptionContext is the same as EditMessageCaption, but with given context.
+func (b *Bot) EditMessageCaptionContext(ctx context.Context, caption string, options OptionsEditMessageCaption) (result APIResponseMessageOrBool) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -753,28 +1085,38 @@ func (b *Bot) EditMessageCaption(caption string, options OptionsEditMessageCapti
 	// essential params
 	options["caption"] = caption
 
-	return b.requestResponseMessageOrBool("editMessageCaption", options)
+	return b.requestResponseMessageOrBool(ctx, "editMessageCaption", options)
 }
 
 // EditMessageMedia edites a media message
 //
 // https://core.telegram.org/bots/api#editmessagemedia
 func (b *Bot) EditMessageMedia(media InputMedia, options OptionsEditMessageMedia) (result APIResponseMessageOrBool) {
+	return b.EditMessageMediaContext(context.Background(), media, options)
+}
+
+// EditMessageMediaContext is the same as EditMessageMedia, but with given context.
+func (b *Bot) EditMessageMediaContext(ctx context.Context, media InputMedia, options OptionsEditMessageMedia) (result APIResponseMessageOrBool) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
 
 	// essential params
-	options["media"] = media
+	options["media"] = attachInputMedia(options, media)[0]
 
-	return b.requestResponseMessageOrBool("editMessageMedia", options)
+	return b.requestResponseMessageOrBool(ctx, "editMessageMedia", options)
 }
 
 // EditMessageReplyMarkup edits reply markup of a message
 //
 // https://core.telegram.org/bots/api#editmessagereplymarkup
 func (b *Bot) EditMessageReplyMarkup(options OptionsEditMessageReplyMarkup) (result APIResponseMessageOrBool) {
-	return b.requestResponseMessageOrBool("editMessageReplyMarkup", options)
+	return b.EditMessageReplyMarkupContext(context.Background(), options)
+}
+
+// EditMessageReplyMarkupContext is the same as EditMessageReplyMarkup, but with given context.
+func (b *Bot) EditMessageReplyMarkupContext(ctx context.Context, options OptionsEditMessageReplyMarkup) (result APIResponseMessageOrBool) {
+	return b.requestResponseMessageOrBool(ctx, "editMessageReplyMarkup", options)
 }
 
 // EditMessageLiveLocation edits live location of a message
@@ -786,6 +1128,11 @@ func (b *Bot) EditMessageReplyMarkup(options OptionsEditMessageReplyMarkup) (res
 //
 // https://core.telegram.org/bots/api#editmessagelivelocation
 func (b *Bot) EditMessageLiveLocation(latitude, longitude float32, options OptionsEditMessageLiveLocation) (result APIResponseMessageOrBool) {
+	return b.EditMessageLiveLocationContext(context.Background(), latitude, longitude, options)
+}
+
+// EditMessageLiveLocationContext is the same as EditMessageLiveLocation, but with given context.
+func (b *Bot) EditMessageLiveLocationContext(ctx context.Context, latitude, longitude float32, options OptionsEditMessageLiveLocation) (result APIResponseMessageOrBool) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -794,7 +1141,7 @@ func (b *Bot) EditMessageLiveLocation(latitude, longitude float32, options Optio
 	options["latitude"] = latitude
 	options["longitude"] = longitude
 
-	return b.requestResponseMessageOrBool("editMessageLiveLocation", options)
+	return b.requestResponseMessageOrBool(ctx, "editMessageLiveLocation", options)
 }
 
 // StopMessageLiveLocation stops live location of a message
@@ -806,14 +1153,24 @@ func (b *Bot) EditMessageLiveLocation(latitude, longitude float32, options Optio
 //
 // https://core.telegram.org/bots/api#stopmessagelivelocation
 func (b *Bot) StopMessageLiveLocation(options OptionsStopMessageLiveLocation) (result APIResponseMessageOrBool) {
-	return b.requestResponseMessageOrBool("stopMessageLiveLocation", options)
+	return b.StopMessageLiveLocationContext(context.Background(), options)
+}
+
+// StopMessageLiveLocationContext is the same as StopMessageLiveLocation, but with given context.
+func (b *Bot) StopMessageLiveLocationContext(ctx context.Context, options OptionsStopMessageLiveLocation) (result APIResponseMessageOrBool) {
+	return b.requestResponseMessageOrBool(ctx, "stopMessageLiveLocation", options)
 }
 
 // DeleteMessage deletes a message
 //
 // https://core.telegram.org/bots/api#deletemessage
 func (b *Bot) DeleteMessage(chatID ChatID, messageID int) (result APIResponseBool) {
-	return b.requestResponseBool("deleteMessage", map[string]interface{}{
+	return b.DeleteMessageContext(context.Background(), chatID, messageID)
+}
+
+// DeleteMessageContext is the same as DeleteMessage, but with given context.
+func (b *Bot) DeleteMessageContext(ctx context.Context, chatID ChatID, messageID int) (result APIResponseBool) {
+	return b.requestResponseBool(ctx, "deleteMessage", map[string]interface{}{
 		"chat_id":    chatID,
 		"message_id": messageID,
 	})
@@ -825,21 +1182,65 @@ func (b *Bot) DeleteMessage(chatID ChatID, messageID int) (result APIResponseBoo
 //
 // https://core.telegram.org/bots/api#answerinlinequery
 func (b *Bot) AnswerInlineQuery(inlineQueryID string, results []interface{}, options OptionsAnswerInlineQuery) (result APIResponseBool) {
+	return b.AnswerInlineQueryContext(context.Background(), inlineQueryID, results, options)
+}
+
+// AnswerInlineQueryContext is the same as AnswerInlineQuery, but with given context.
+func (b *Bot) AnswerInlineQueryContext(ctx context.Context, inlineQueryID string, results []interface{}, options OptionsAnswerInlineQuery) (result APIResponseBool) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
 
+	if err := validateInlineQueryResults(results); err != nil {
+		b.error(err.Error())
+
+		result.APIResponseBase = errorResponseBase(err, 0)
+		return result
+	}
+
 	// essential params
 	options["inline_query_id"] = inlineQueryID
 	options["results"] = results
 
-	return b.requestResponseBool("answerInlineQuery", options)
+	return b.requestResponseBool(ctx, "answerInlineQuery", options)
+}
+
+// AnswerInlineQueryWithPagination sends a page of results (provided by given provider) to an inline query,
+// with the offset of the next page. (`InlineQuery.Offset` is used as the offset of the current page)
+//
+// pageSize should be between 1 and 50.
+//
+// https://core.telegram.org/bots/api#answerinlinequery
+func (b *Bot) AnswerInlineQueryWithPagination(query InlineQuery, pageSize int, provider InlineQueryResultsProvider, options OptionsAnswerInlineQuery) (result APIResponseBool) {
+	return b.AnswerInlineQueryWithPaginationContext(context.Background(), query, pageSize, provider, options)
+}
+
+// AnswerInlineQueryWithPaginationContext is the same as AnswerInlineQueryWithPagination, but with given context.
+func (b *Bot) AnswerInlineQueryWithPaginationContext(ctx context.Context, query InlineQuery, pageSize int, provider InlineQueryResultsProvider, options OptionsAnswerInlineQuery) (result APIResponseBool) {
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+
+	results, nextOffset, err := paginateInlineQueryResults(query, pageSize, provider)
+	if err != nil {
+		b.error(err.Error())
+
+		result.APIResponseBase = errorResponseBase(err, 0)
+		return result
+	}
+
+	return b.AnswerInlineQueryContext(ctx, query.ID, results, options.SetNextOffset(nextOffset))
 }
 
 // SendInvoice sends an invoice.
 //
 // https://core.telegram.org/bots/api#sendinvoice
 func (b *Bot) SendInvoice(chatID int64, title, description, payload, providerToken, startParameter, currency string, prices []LabeledPrice, options OptionsSendInvoice) (result APIResponseMessage) {
+	return b.SendInvoiceContext(context.Background(), chatID, title, description, payload, providerToken, startParameter, currency, prices, options)
+}
+
+// SendInvoiceContext is the same as SendInvoice, but with given context.
+func (b *Bot) SendInvoiceContext(ctx context.Context, chatID int64, title, description, payload, providerToken, startParameter, currency string, prices []LabeledPrice, options OptionsSendInvoice) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -854,7 +1255,7 @@ func (b *Bot) SendInvoice(chatID int64, title, description, payload, providerTok
 	options["currency"] = currency
 	options["prices"] = prices
 
-	return b.requestResponseMessage("sendInvoice", options)
+	return b.requestResponseMessage(ctx, "sendInvoice", options)
 }
 
 // AnswerShippingQuery answers a shipping query.
@@ -864,6 +1265,11 @@ func (b *Bot) SendInvoice(chatID int64, title, description, payload, providerTok
 //
 // https://core.telegram.org/bots/api#answershippingquery
 func (b *Bot) AnswerShippingQuery(shippingQueryID string, ok bool, shippingOptions []ShippingOption, errorMessage *string) (result APIResponseBool) {
+	return b.AnswerShippingQueryContext(context.Background(), shippingQueryID, ok, shippingOptions, errorMessage)
+}
+
+// AnswerShippingQueryContext is the same as AnswerShippingQuery, but with given context.
+func (b *Bot) AnswerShippingQueryContext(ctx context.Context, shippingQueryID string, ok bool, shippingOptions []ShippingOption, errorMessage *string) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"shipping_query_id": shippingQueryID,
@@ -880,13 +1286,18 @@ func (b *Bot) AnswerShippingQuery(shippingQueryID string, ok bool, shippingOptio
 		}
 	}
 
-	return b.requestResponseBool("answerShippingQuery", params)
+	return b.requestResponseBool(ctx, "answerShippingQuery", params)
 }
 
 // AnswerPreCheckoutQuery answers a pre-checkout query.
 //
 // https://core.telegram.org/bots/api#answerprecheckoutquery
 func (b *Bot) AnswerPreCheckoutQuery(preCheckoutQueryID string, ok bool, errorMessage *string) (result APIResponseBool) {
+	return b.AnswerPreCheckoutQueryContext(context.Background(), preCheckoutQueryID, ok, errorMessage)
+}
+
+// AnswerPreCheckoutQueryContext is the same as AnswerPreCheckoutQuery, but with given context.
+func (b *Bot) AnswerPreCheckoutQueryContext(ctx context.Context, preCheckoutQueryID string, ok bool, errorMessage *string) (result APIResponseBool) {
 	// essential params
 	params := map[string]interface{}{
 		"pre_checkout_query_id": preCheckoutQueryID,
@@ -899,13 +1310,18 @@ func (b *Bot) AnswerPreCheckoutQuery(preCheckoutQueryID string, ok bool, errorMe
 		}
 	}
 
-	return b.requestResponseBool("answerPreCheckoutQuery", params)
+	return b.requestResponseBool(ctx, "answerPreCheckoutQuery", params)
 }
 
 // SendGame sends a game.
 //
 // https://core.telegram.org/bots/api#sendgame
 func (b *Bot) SendGame(chatID ChatID, gameShortName string, options OptionsSendGame) (result APIResponseMessage) {
+	return b.SendGameContext(context.Background(), chatID, gameShortName, options)
+}
+
+// SendGameContext is the same as SendGame, but with given context.
+func (b *Bot) SendGameContext(ctx context.Context, chatID ChatID, gameShortName string, options OptionsSendGame) (result APIResponseMessage) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -914,7 +1330,7 @@ func (b *Bot) SendGame(chatID ChatID, gameShortName string, options OptionsSendG
 	options["chat_id"] = chatID
 	options["game_short_name"] = gameShortName
 
-	return b.requestResponseMessage("sendGame", options)
+	return b.requestResponseMessage(ctx, "sendGame", options)
 }
 
 // SetGameScore sets score of a game.
@@ -926,6 +1342,11 @@ func (b *Bot) SendGame(chatID ChatID, gameShortName string, options OptionsSendG
 //
 // https://core.telegram.org/bots/api#setgamescore
 func (b *Bot) SetGameScore(userID int, score int, options OptionsSetGameScore) (result APIResponseMessageOrBool) {
+	return b.SetGameScoreContext(context.Background(), userID, score, options)
+}
+
+// SetGameScoreContext is the same as SetGameScore, but with given context.
+func (b *Bot) SetGameScoreContext(ctx context.Context, userID int, score int, options OptionsSetGameScore) (result APIResponseMessageOrBool) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -934,7 +1355,7 @@ func (b *Bot) SetGameScore(userID int, score int, options OptionsSetGameScore) (
 	options["user_id"] = userID
 	options["score"] = score
 
-	return b.requestResponseMessageOrBool("setGameScore", options)
+	return b.requestResponseMessageOrBool(ctx, "setGameScore", options)
 }
 
 // GetGameHighScores gets high scores of a game.
@@ -944,6 +1365,11 @@ func (b *Bot) SetGameScore(userID int, score int, options OptionsSetGameScore) (
 //
 // https://core.telegram.org/bots/api#getgamehighscores
 func (b *Bot) GetGameHighScores(userID int, options OptionsGetGameHighScores) (result APIResponseGameHighScores) {
+	return b.GetGameHighScoresContext(context.Background(), userID, options)
+}
+
+// GetGameHighScoresContext is the same as GetGameHighScores, but with given context.
+func (b *Bot) GetGameHighScoresContext(ctx context.Context, userID int, options OptionsGetGameHighScores) (result APIResponseGameHighScores) {
 	if options == nil {
 		options = map[string]interface{}{}
 	}
@@ -951,7 +1377,7 @@ func (b *Bot) GetGameHighScores(userID int, options OptionsGetGameHighScores) (r
 	// essential params
 	options["user_id"] = userID
 
-	return b.requestResponseGameHighScores("getGameHighScores", options)
+	return b.requestResponseGameHighScores(ctx, "getGameHighScores", options)
 }
 
 // Check if given http params contain file or not.
@@ -961,7 +1387,23 @@ func checkIfFileParamExists(params map[string]interface{}) bool {
 		case *os.File, []byte:
 			return true
 		case InputFile:
-			if len(value.(InputFile).Bytes) > 0 || value.(InputFile).Filepath != nil {
+			if value.(InputFile).isUploadable() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Check if given http params contain file which cannot be read again. (so the request cannot be retried)
+func hasOneTimeFileParam(params map[string]interface{}) bool {
+	for _, value := range params {
+		switch v := value.(type) {
+		case *os.File:
+			return true
+		case InputFile:
+			if v.Reader != nil {
 				return true
 			}
 		}
@@ -1031,133 +1473,78 @@ func (b *Bot) paramToString(param interface{}) (result string, success bool) {
 
 // Send request to API server and return the response as bytes(synchronously).
 //
-// NOTE: If *os.File is included in the params, it will be closed automatically in this function.
-func (b *Bot) request(method string, params map[string]interface{}) (respBytes []byte, err error) {
-	apiURL := fmt.Sprintf("%s%s/%s", apiBaseURL, b.token, method)
+// The request will be aborted when given context is canceled or its deadline is exceeded.
+//
+// Requests which send messages will be paced by the rate limiter of this bot,
+// and failed requests will be retried according to its retry policy.
+//
+// Requests to migrated chats will be handled according to its chat migration configuration.
+func (b *Bot) request(ctx context.Context, method string, params map[string]interface{}) (respBytes []byte, statusCode int, err error) {
+	b.replaceMigratedChatID(params)
 
-	b.verbose("sending request to api url: %s, params: %#v", apiURL, params)
+	var migrated bool
+	for attempt := 1; ; attempt++ {
+		if err = b.applyRateLimit(ctx, method, params); err != nil {
+			return []byte{}, 0, err
+		}
 
-	if checkIfFileParamExists(params) { // multipart form data
-		body := &bytes.Buffer{}
-		writer := multipart.NewWriter(body)
+		respBytes, statusCode, err = b.requestOnce(ctx, method, params)
 
-		for key, value := range params {
-			switch value.(type) {
-			case *os.File:
-				if file, ok := value.(*os.File); ok {
-					defer file.Close()
-
-					var part io.Writer
-					part, err = writer.CreateFormFile(key, file.Name())
-					if err == nil {
-						if _, err = io.Copy(part, file); err != nil {
-							b.error("could not write to multipart: %s", key)
-						}
-					} else {
-						b.error("could not create form file for parameter '%s' (%v)", key, value)
-					}
-				} else {
-					b.error("parameter '%s' (%v) could not be cast to file", key, value)
-				}
-			case []byte:
-				if fbytes, ok := value.([]byte); ok {
-					filename := fmt.Sprintf("%s.%s", key, getExtension(fbytes))
-					var part io.Writer
-					part, err = writer.CreateFormFile(key, filename)
-					if err == nil {
-						if _, err = io.Copy(part, bytes.NewReader(fbytes)); err != nil {
-							b.error("could not write to multipart: %s", key)
-						}
-					} else {
-						b.error("could not create form file for parameter '%s' ([]byte)", key)
-					}
-				} else {
-					b.error("parameter '%s' could not be cast to []byte", key)
-				}
-			case InputFile:
-				if inputFile, ok := value.(InputFile); ok {
-					if inputFile.Filepath != nil {
-						var file *os.File
-						if file, err = os.Open(*inputFile.Filepath); err == nil {
-							defer file.Close()
-
-							var part io.Writer
-							part, err = writer.CreateFormFile(key, file.Name())
-							if err == nil {
-								if _, err = io.Copy(part, file); err != nil {
-									b.error("could not write to multipart: %s", key)
-								}
-							} else {
-								b.error("could not create form file for parameter '%s' (%v)", key, value)
-							}
-						} else {
-							b.error("parameter '%s' (%v) could not be read from file: %s", key, value, err.Error())
-						}
-					} else if len(inputFile.Bytes) > 0 {
-						filename := fmt.Sprintf("%s.%s", key, getExtension(inputFile.Bytes))
-						var part io.Writer
-						part, err = writer.CreateFormFile(key, filename)
-						if err == nil {
-							if _, err = io.Copy(part, bytes.NewReader(inputFile.Bytes)); err != nil {
-								b.error("could not write InputFile to multipart: %s", key)
-							}
-						} else {
-							b.error("could not create form file for parameter '%s' (InputFile)", key)
-						}
-					} else {
-						if strValue, ok := b.paramToString(value); ok {
-							writer.WriteField(key, strValue)
-						} else {
-							b.error("invalid InputFile parameter '%s'", key)
-						}
-					}
-				} else {
-					b.error("parameter '%s' could not be cast to InputFile", key)
-				}
-			default:
-				if strValue, ok := b.paramToString(value); ok {
-					writer.WriteField(key, strValue)
-				}
-			}
+		// retry once with the new chat id (not counted as an attempt)
+		if err == nil && !migrated && b.handleMigrationFailure(params, respBytes, statusCode) {
+			migrated = true
+			attempt--
+
+			continue
 		}
 
-		if err = writer.Close(); err != nil {
-			b.error("error while closing writer (%s)", err)
+		if b.retryPolicy == nil || ctx.Err() != nil {
+			return respBytes, statusCode, err
 		}
 
-		var req *http.Request
-		req, err = http.NewRequest("POST", apiURL, body)
-		if err == nil {
-			req.Header.Add("Content-Type", writer.FormDataContentType()) // due to file parameter
-			req.Close = true
+		wait, retry := b.retryPolicy.retryAfter(method, params, attempt, respBytes, statusCode, err)
+		if !retry {
+			return respBytes, statusCode, err
+		}
 
-			var resp *http.Response
-			resp, err = b.httpClient.Do(req)
+		b.verbose("retrying %s in %s (attempt: %d/%d)", method, wait, attempt+1, b.retryPolicy.MaxAttempts)
 
-			if resp != nil { // XXX - in case of http redirect
-				defer resp.Body.Close()
-			}
+		if sleepContext(ctx, wait) != nil {
+			return respBytes, statusCode, err
+		}
+	}
+}
+
+// Send request to API server once, and return the response as bytes(synchronously).
+//
+// Files in the params are streamed with multipart form data, without being buffered in memory.
+//
+// NOTE: If *os.File is included in the params, it will be closed automatically in this function.
+func (b *Bot) requestOnce(ctx context.Context, method string, params map[string]interface{}) (respBytes []byte, statusCode int, err error) {
+	for _, value := range params {
+		if file, ok := value.(*os.File); ok {
+			defer file.Close()
+		}
+	}
+
+	apiURL := b.getAPIURL(method)
 
-			if err == nil {
-				// FIXXX: check http status code here
-				var bytes []byte
-				bytes, err = ioutil.ReadAll(resp.Body)
-				if err == nil {
-					return bytes, nil
-				}
+	b.verbose("sending request to api url: %s, params: %#v", apiURL, params)
 
-				err = fmt.Errorf("response read error: %s", err)
+	var req *http.Request
 
-				b.error(err.Error())
-			} else {
-				err = fmt.Errorf("request error: %s", err)
+	if checkIfFileParamExists(params) { // multipart form data (streamed)
+		var body io.Reader
+		var contentType string
+		var contentLength int64
+		var closeBody func()
+		if body, contentType, contentLength, closeBody, err = b.multipartBody(ctx, params); err == nil {
+			defer closeBody()
 
-				b.error(err.Error())
+			if req, err = http.NewRequest("POST", apiURL, body); err == nil {
+				req.Header.Add("Content-Type", contentType) // due to file parameter
+				req.ContentLength = contentLength
 			}
-		} else {
-			err = fmt.Errorf("building request error: %s", err)
-
-			b.error(err.Error())
 		}
 	} else { // www-form urlencoded
 		paramValues := url.Values{}
@@ -1168,372 +1555,205 @@ func (b *Bot) request(method string, params map[string]interface{}) (respBytes [
 		}
 		encoded := paramValues.Encode()
 
-		var req *http.Request
-		req, err = http.NewRequest("POST", apiURL, bytes.NewBufferString(encoded))
-		if err == nil {
+		if req, err = http.NewRequest("POST", apiURL, bytes.NewBufferString(encoded)); err == nil {
 			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 			req.Header.Add("Content-Length", strconv.Itoa(len(encoded)))
-			req.Close = true
+		}
+	}
 
-			var resp *http.Response
-			resp, err = b.httpClient.Do(req)
+	if err != nil {
+		err = fmt.Errorf("building request error: %s", b.redactError(err))
 
-			if resp != nil { // XXX - in case of redirect
-				defer resp.Body.Close()
-			}
+		b.error(err.Error())
 
-			if err == nil {
-				// FIXXX: check http status code here
-				var bytes []byte
-				bytes, err = ioutil.ReadAll(resp.Body)
-				if err == nil {
-					return bytes, nil
-				}
+		return []byte{}, 0, &RequestError{Method: method, Err: err}
+	}
 
-				err = fmt.Errorf("response read error: %s", err)
+	req = req.WithContext(ctx)
+	req.Close = true
 
-				b.error(err.Error())
-			} else {
-				err = fmt.Errorf("request error: %s", err)
+	httpClient := b.httpClient
+	if method == "getUpdates" {
+		httpClient = b.getLongPollingClient()
+	}
 
-				b.error(err.Error())
-			}
-		} else {
-			err = fmt.Errorf("building request error: %s", err)
+	var resp *http.Response
+	resp, err = httpClient.Do(req)
 
-			b.error(err.Error())
-		}
+	if resp != nil { // XXX - in case of http redirect
+		defer resp.Body.Close()
 	}
 
-	return []byte{}, fmt.Errorf(b.redact(err.Error()))
-}
-
-// Send request for APIResponseWebhookInfo and fetch its result.
-func (b *Bot) requestResponseWebhookInfo() (result APIResponseWebhookInfo) {
-	var errStr string
+	if err != nil {
+		err = b.redactError(err) // url contains the token
 
-	if bytes, err := b.request("getWebhookInfo", map[string]interface{}{}); err == nil {
-		var jsonResponse APIResponseWebhookInfo
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
+		b.error("request error: %s", err)
 
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("getWebhookInfo failed with error: %s", err)
+		return []byte{}, 0, &RequestError{Method: method, Err: err}
 	}
 
-	b.error(errStr)
+	var bytes []byte
+	if bytes, err = ioutil.ReadAll(resp.Body); err != nil {
+		b.error("response read error: %s", err)
+
+		return []byte{}, resp.StatusCode, &RequestError{Method: method, StatusCode: resp.StatusCode, Err: err}
+	}
 
-	return APIResponseWebhookInfo{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return bytes, resp.StatusCode, nil
 }
 
-// Send request for APIResponseUser and fetch its result.
-func (b *Bot) requestResponseUser(method string, params map[string]interface{}) (result APIResponseUser) {
-	var errStr string
+// Send request and parse its response into given result.
+//
+// base should be the APIResponseBase embedded in result, for keeping errors.
+func (b *Bot) requestAndParse(ctx context.Context, method string, params map[string]interface{}, result interface{}, base *APIResponseBase) {
+	bytes, statusCode, err := b.request(ctx, method, params)
+	if err == nil {
+		if err = json.Unmarshal(bytes, result); err == nil {
+			base.statusCode = statusCode
 
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseUser
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
+			return
 		}
 
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
+		err = &RequestError{
+			Method:     method,
+			StatusCode: statusCode,
+			Err:        fmt.Errorf("json parse error: %s (%s)", err, string(bytes)),
+		}
 	}
 
-	b.error(errStr)
+	b.error(err.Error())
 
-	return APIResponseUser{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	*base = errorResponseBase(err, statusCode)
 }
 
-// Send request for APIResponseMessage and fetch its result.
-func (b *Bot) requestResponseMessage(method string, params map[string]interface{}) (result APIResponseMessage) {
-	var errStr string
-
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseMessage
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
+// Generate a failed APIResponseBase with given error.
+func errorResponseBase(err error, statusCode int) APIResponseBase {
+	errStr := err.Error()
 
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
-	}
+	return APIResponseBase{Ok: false, Description: &errStr, statusCode: statusCode, err: err}
+}
 
-	b.error(errStr)
+// Send request for APIResponseWebhookInfo and fetch its result.
+func (b *Bot) requestResponseWebhookInfo(ctx context.Context) (result APIResponseWebhookInfo) {
+	b.requestAndParse(ctx, "getWebhookInfo", map[string]interface{}{}, &result, &result.APIResponseBase)
 
-	return APIResponseMessage{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
-// Send request for APIResponseMessages and fetch its result.
-func (b *Bot) requestResponseMessages(method string, params map[string]interface{}) (result APIResponseMessages) {
-	var errStr string
+// Send request for APIResponseUser and fetch its result.
+func (b *Bot) requestResponseUser(ctx context.Context, method string, params map[string]interface{}) (result APIResponseUser) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseMessages
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
+	return result
+}
 
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
+// Send request for APIResponseMessage and fetch its result.
+func (b *Bot) requestResponseMessage(ctx context.Context, method string, params map[string]interface{}) (result APIResponseMessage) {
+	if b.fileIDCache != nil {
+		return b.requestResponseMessageWithFileIDCache(ctx, method, params)
 	}
 
-	b.error(errStr)
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-	return APIResponseMessages{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
-// Send request for APIResponseUserProfilePhotos and fetch its result.
-func (b *Bot) requestResponseUserProfilePhotos(method string, params map[string]interface{}) (result APIResponseUserProfilePhotos) {
-	var errStr string
-
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseUserProfilePhotos
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
+// Send request for APIResponseMessages and fetch its result.
+func (b *Bot) requestResponseMessages(ctx context.Context, method string, params map[string]interface{}) (result APIResponseMessages) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
-	}
+	return result
+}
 
-	b.error(errStr)
+// Send request for APIResponseUserProfilePhotos and fetch its result.
+func (b *Bot) requestResponseUserProfilePhotos(ctx context.Context, method string, params map[string]interface{}) (result APIResponseUserProfilePhotos) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-	return APIResponseUserProfilePhotos{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
 // Send request for APIResponseUpdates and fetch its result.
-func (b *Bot) requestResponseUpdates(method string, params map[string]interface{}) (result APIResponseUpdates) {
-	var errStr string
+func (b *Bot) requestResponseUpdates(ctx context.Context, method string, params map[string]interface{}) (result APIResponseUpdates) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseUpdates
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
-
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
-	}
-
-	b.error(errStr)
-
-	return APIResponseUpdates{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
 // Send request for APIResponseFile and fetch its result.
-func (b *Bot) requestResponseFile(method string, params map[string]interface{}) (result APIResponseFile) {
-	var errStr string
+func (b *Bot) requestResponseFile(ctx context.Context, method string, params map[string]interface{}) (result APIResponseFile) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseFile
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
-
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
-	}
-
-	b.error(errStr)
-
-	return APIResponseFile{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
 // Send request for APIResponseChat and fetch its result.
-func (b *Bot) requestResponseChat(method string, params map[string]interface{}) (result APIResponseChat) {
-	var errStr string
-
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseChat
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
+func (b *Bot) requestResponseChat(ctx context.Context, method string, params map[string]interface{}) (result APIResponseChat) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
-	}
-
-	b.error(errStr)
-
-	return APIResponseChat{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
 // Send request for APIResponseChatAdministrator and fetch its result.
-func (b *Bot) requestResponseChatAdministrators(method string, params map[string]interface{}) (result APIResponseChatAdministrators) {
-	var errStr string
-
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseChatAdministrators
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
-
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
-	}
-
-	b.error(errStr)
+func (b *Bot) requestResponseChatAdministrators(ctx context.Context, method string, params map[string]interface{}) (result APIResponseChatAdministrators) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-	return APIResponseChatAdministrators{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
 // Send request for APIResponseChatMember and fetch its result.
-func (b *Bot) requestResponseChatMember(method string, params map[string]interface{}) (result APIResponseChatMember) {
-	var errStr string
-
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseChatMember
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
-
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
-	}
-
-	b.error(errStr)
+func (b *Bot) requestResponseChatMember(ctx context.Context, method string, params map[string]interface{}) (result APIResponseChatMember) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-	return APIResponseChatMember{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
 // Send request for APIResponseInt and fetch its result.
-func (b *Bot) requestResponseInt(method string, params map[string]interface{}) (result APIResponseInt) {
-	var errStr string
+func (b *Bot) requestResponseInt(ctx context.Context, method string, params map[string]interface{}) (result APIResponseInt) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseInt
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
-
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
-	}
-
-	b.error(errStr)
-
-	return APIResponseInt{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
 // Send request for APIResponseBool and fetch its result.
-func (b *Bot) requestResponseBool(method string, params map[string]interface{}) (result APIResponseBool) {
-	var errStr string
-
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseBool
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
+func (b *Bot) requestResponseBool(ctx context.Context, method string, params map[string]interface{}) (result APIResponseBool) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
-	}
-
-	b.error(errStr)
-
-	return APIResponseBool{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
 // Send request for APIResponseString and fetch its result.
-func (b *Bot) requestResponseString(method string, params map[string]interface{}) (result APIResponseString) {
-	var errStr string
-
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseString
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
+func (b *Bot) requestResponseString(ctx context.Context, method string, params map[string]interface{}) (result APIResponseString) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
-	}
-
-	b.error(errStr)
-
-	return APIResponseString{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
 // Send request for APIResponseGameHighScores and fetch its result.
-func (b *Bot) requestResponseGameHighScores(method string, params map[string]interface{}) (result APIResponseGameHighScores) {
-	var errStr string
-
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseGameHighScores
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
-
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
-	}
-
-	b.error(errStr)
+func (b *Bot) requestResponseGameHighScores(ctx context.Context, method string, params map[string]interface{}) (result APIResponseGameHighScores) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-	return APIResponseGameHighScores{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
 // Send request for APIResponseStickerSet and fetch its result.
-func (b *Bot) requestResponseStickerSet(method string, params map[string]interface{}) (result APIResponseStickerSet) {
-	var errStr string
-
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponseStickerSet
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
-
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
-	}
-
-	b.error(errStr)
+func (b *Bot) requestResponseStickerSet(ctx context.Context, method string, params map[string]interface{}) (result APIResponseStickerSet) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-	return APIResponseStickerSet{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
 // Send request for APIResponseMessageOrBool and fetch its result.
-func (b *Bot) requestResponseMessageOrBool(method string, params map[string]interface{}) (result APIResponseMessageOrBool) {
-	var errStr string
-
-	if bytes, err := b.request(method, params); err == nil {
+func (b *Bot) requestResponseMessageOrBool(ctx context.Context, method string, params map[string]interface{}) (result APIResponseMessageOrBool) {
+	bytes, statusCode, err := b.request(ctx, method, params)
+	if err == nil {
 		// try APIResponseMessage type,
 		var jsonResponseMessage APIResponseMessage
 		err = json.Unmarshal(bytes, &jsonResponseMessage)
 		if err == nil {
+			jsonResponseMessage.statusCode = statusCode
+
 			return APIResponseMessageOrBool{
-				APIResponseBase: APIResponseBase{Ok: true, Description: jsonResponseMessage.Description},
+				APIResponseBase: jsonResponseMessage.APIResponseBase,
 				ResultMessage:   jsonResponseMessage.Result,
 			}
 		}
@@ -1542,75 +1762,112 @@ func (b *Bot) requestResponseMessageOrBool(method string, params map[string]inte
 		var jsonResponseBool APIResponseBool
 		err = json.Unmarshal(bytes, &jsonResponseBool)
 		if err == nil {
+			jsonResponseBool.statusCode = statusCode
+
 			return APIResponseMessageOrBool{
-				APIResponseBase: APIResponseBase{Ok: true, Description: jsonResponseBool.Description},
+				APIResponseBase: jsonResponseBool.APIResponseBase,
 				ResultBool:      &jsonResponseBool.Result,
 			}
 		}
 
-		errStr = fmt.Sprintf("json parse error: not in Message nor bool type (%s)", string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
+		err = &RequestError{
+			Method:     method,
+			StatusCode: statusCode,
+			Err:        fmt.Errorf("json parse error: not in Message nor bool type (%s)", string(bytes)),
+		}
 	}
 
-	b.error(errStr)
+	b.error(err.Error())
+
+	errStr := err.Error()
 
-	return APIResponseMessageOrBool{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return APIResponseMessageOrBool{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr, statusCode: statusCode, err: err}}
 }
 
 // Send request for APIResponsePoll and fetch its result.
-func (b *Bot) requestResponsePoll(method string, params map[string]interface{}) (result APIResponsePoll) {
-	var errStr string
-
-	if bytes, err := b.request(method, params); err == nil {
-		var jsonResponse APIResponsePoll
-		err = json.Unmarshal(bytes, &jsonResponse)
-		if err == nil {
-			return jsonResponse
-		}
-
-		errStr = fmt.Sprintf("json parse error: %s (%s)", err, string(bytes))
-	} else {
-		errStr = fmt.Sprintf("%s failed with error: %s", method, err)
-	}
+func (b *Bot) requestResponsePoll(ctx context.Context, method string, params map[string]interface{}) (result APIResponsePoll) {
+	b.requestAndParse(ctx, method, params, &result, &result.APIResponseBase)
 
-	b.error(errStr)
-
-	return APIResponsePoll{APIResponseBase: APIResponseBase{Ok: false, Description: &errStr}}
+	return result
 }
 
-// Handle Webhook request.
-func (b *Bot) handleWebhook(writer http.ResponseWriter, req *http.Request) {
+// Handle Webhook request with given update handler.
+//
+// If handlers is given, it will be used for tracking running update handlers.
+func (b *Bot) handleWebhook(writer http.ResponseWriter, req *http.Request, updateHandler UpdateHandler, handlers *sync.WaitGroup) {
 	defer req.Body.Close()
 
 	b.verbose("received webhook request: %+v", req)
 
+	if !b.isAllowedWebhookRequest(req) {
+		http.Error(writer, "forbidden", http.StatusForbidden)
+
+		return
+	}
+
+	if req.Method != http.MethodPost {
+		writer.Header().Set("Allow", http.MethodPost)
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	var done func(handled bool)
+	if handlers != nil {
+		handlers.Add(1)
+		done = func(_ bool) { handlers.Done() }
+	}
+
 	if body, err := ioutil.ReadAll(req.Body); err == nil {
 		var webhook Update
 		if err = json.Unmarshal(body, &webhook); err != nil {
 			b.error("error while parsing json (%s)", err)
+
+			if done != nil {
+				done(false)
+			}
+
+			http.Error(writer, "malformed update", http.StatusBadRequest)
 		} else {
 			b.verbose("received webhook body: %s", string(body))
 
-			b.updateHandler(b, webhook, nil)
+			b.handleMigrationUpdate(webhook)
+
+			if err = b.dispatchUpdate(req.Context(), updateHandler, webhook, nil, done, true); err != nil {
+				b.error("error while dispatching update (%s)", err)
+
+				http.Error(writer, "failed to dispatch update", http.StatusServiceUnavailable)
+
+				return
+			}
+
+			writer.WriteHeader(http.StatusOK)
 		}
 	} else {
 		b.error("error while reading webhook request (%s)", err)
 
-		b.updateHandler(b, Update{}, err)
+		b.dispatchUpdate(req.Context(), updateHandler, Update{}, err, done, true)
+
+		http.Error(writer, "failed to read request", http.StatusBadRequest)
 	}
 }
 
-// get file extension from bytes array
+// get file extension from content type
 //
 // https://www.w3.org/Protocols/rfc1341/4_Content-Type.html
-func getExtension(bytes []byte) string {
-	types := strings.Split(http.DetectContentType(bytes), "/") // ex: "image/jpeg"
+func getExtension(contentType string) string {
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0]) // for removing subtype parameter
+
+	switch mediaType {
+	case "application/octet-stream":
+		return "bin"
+	case "text/plain":
+		return "txt"
+	}
+
+	types := strings.Split(mediaType, "/") // ex: "image/jpeg"
 	if len(types) >= 2 {
-		splitted := strings.Split(types[1], ";") // for removing subtype parameter
-		if len(splitted) >= 1 {
-			return splitted[0] // return subtype only
-		}
+		return types[1] // return subtype only
 	}
 	return "" // default
 }
@@ -36,6 +36,42 @@ func (o OptionsGetUpdates) SetAllowedUpdates(allowedUpdates []AllowedUpdate) Opt
 	return o
 }
 
+// OptionsSetWebhook struct for SetWebhookHost() and SetWebhookURL().
+//
+// options include: certificate, max_connections, allowed_updates, and secret_token.
+//
+// https://core.telegram.org/bots/api#setwebhook
+type OptionsSetWebhook MethodOptions
+
+// SetCertificate sets the certificate value of OptionsSetWebhook.
+//
+// certFilepath is the path of a public key certificate (.pem), needed only for self-signed certificates.
+func (o OptionsSetWebhook) SetCertificate(certFilepath string) OptionsSetWebhook {
+	o["certificate"] = InputFileFromFilepath(certFilepath)
+	return o
+}
+
+// SetMaxConnections sets the max_connections value of OptionsSetWebhook.
+func (o OptionsSetWebhook) SetMaxConnections(maxConnections int) OptionsSetWebhook {
+	o["max_connections"] = maxConnections
+	return o
+}
+
+// SetAllowedUpdates sets the allowed_updates value of OptionsSetWebhook.
+func (o OptionsSetWebhook) SetAllowedUpdates(allowedUpdates []UpdateType) OptionsSetWebhook {
+	o["allowed_updates"] = allowedUpdates
+	return o
+}
+
+// SetSecretToken sets the secret_token value of OptionsSetWebhook.
+//
+// secretToken should be 1-256 characters of A-Z, a-z, 0-9, _, and -.
+// It will be sent in `X-Telegram-Bot-Api-Secret-Token` header of each webhook request, and verified by the bot.
+func (o OptionsSetWebhook) SetSecretToken(secretToken string) OptionsSetWebhook {
+	o["secret_token"] = secretToken
+	return o
+}
+
 // OptionsSendMessage struct for SendMessage().
 //
 // options include: parse_mode, disable_web_page_preview, disable_notification, reply_to_message_id, and reply_markup.
@@ -828,6 +864,18 @@ func (o OptionsPinChatMessage) SetDisableNotification(disable bool) OptionsPinCh
 // https://core.telegram.org/bots/api#answercallbackquery
 type OptionsAnswerCallbackQuery MethodOptions
 
+// SetText sets the text value of OptionsAnswerCallbackQuery.
+func (o OptionsAnswerCallbackQuery) SetText(text string) OptionsAnswerCallbackQuery {
+	o["text"] = text
+	return o
+}
+
+// SetShowAlert sets the show_alert value of OptionsAnswerCallbackQuery.
+func (o OptionsAnswerCallbackQuery) SetShowAlert(showAlert bool) OptionsAnswerCallbackQuery {
+	o["show_alert"] = showAlert
+	return o
+}
+
 // SetURL sets the url value of OptionsAnswerCallbackQuery.
 func (o OptionsAnswerCallbackQuery) SetURL(url string) OptionsAnswerCallbackQuery {
 	o["url"] = url
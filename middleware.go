@@ -0,0 +1,76 @@
+package telegrambot
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// Middleware is a function type for wrapping update handlers with cross-cutting behaviors. (eg. recovery, logging, auth checks, ...)
+type Middleware func(next UpdateHandler) UpdateHandler
+
+// Use appends given middlewares, which will wrap the update handler for both polling and webhook.
+//
+// Middlewares are applied in the given order, so the first one will be the outermost.
+// It should be called before starting polling or webhook.
+func (b *Bot) Use(middlewares ...Middleware) {
+	b.middlewares = append(b.middlewares, middlewares...)
+}
+
+// Wrap given update handler with the middlewares of this bot.
+func (b *Bot) wrapHandler(handler UpdateHandler) UpdateHandler {
+	for i := len(b.middlewares) - 1; i >= 0; i-- {
+		handler = b.middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// RecoveryMiddleware returns a middleware which recovers from panics in update handlers, and passes them to given function.
+//
+// Panics in update handlers are always recovered and logged by the bot, so this middleware is needed only for
+// handling them in your own way with onPanic. (eg. reporting them to an error tracker)
+func RecoveryMiddleware(onPanic func(b *Bot, update Update, recovered interface{}, stack []byte)) Middleware {
+	return func(next UpdateHandler) UpdateHandler {
+		return func(b *Bot, update Update, err error) {
+			defer b.recoverHandlerPanic(update, onPanic)
+
+			next(b, update, err)
+		}
+	}
+}
+
+// Recover from a panic in update handler, and pass it with the stack trace to given function. (if nil, they will be logged)
+//
+// It should be deferred directly, eg. `defer b.recoverHandlerPanic(update, nil)`.
+func (b *Bot) recoverHandlerPanic(update Update, onPanic func(b *Bot, update Update, recovered interface{}, stack []byte)) {
+	if recovered := recover(); recovered != nil {
+		stack := debug.Stack()
+
+		if onPanic != nil {
+			onPanic(b, update, recovered, stack)
+		} else {
+			b.error("recovered from panic in update handler (update id: %d): %v\n%s", update.UpdateID, recovered, stack)
+		}
+	}
+}
+
+// LoggingMiddleware returns a middleware which logs the type, id, and elapsed time of each handled update.
+func LoggingMiddleware() Middleware {
+	return func(next UpdateHandler) UpdateHandler {
+		return func(b *Bot, update Update, err error) {
+			started := time.Now()
+
+			next(b, update, err)
+
+			if err != nil {
+				b.info("handled error: %s (%s)", err, time.Since(started))
+			} else {
+				updateType := update.Type()
+				if updateType == "" {
+					updateType = "unknown"
+				}
+				b.info("handled update (type: %s, id: %d) (%s)", updateType, update.UpdateID, time.Since(started))
+			}
+		}
+	}
+}
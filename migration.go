@@ -0,0 +1,169 @@
+package telegrambot
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// ChatMigrationStore is an interface for storing chat ids of groups which were migrated to supergroups.
+//
+// Requests to migrated groups are redirected to their supergroups with it,
+// so a persistent one lets the bot skip failed requests to old chat ids after restarts.
+type ChatMigrationStore interface {
+	// Get returns the new chat id of given (migrated) chat id.
+	Get(fromChatID int64) (toChatID int64, exists bool)
+
+	// Set stores the new chat id of given (migrated) chat id.
+	Set(fromChatID, toChatID int64) error
+}
+
+// in-memory implementation of ChatMigrationStore
+type memoryChatMigrationStore struct {
+	migrations *memoryMap
+}
+
+// NewMemoryChatMigrationStore returns a new in-memory ChatMigrationStore.
+func NewMemoryChatMigrationStore() ChatMigrationStore {
+	return &memoryChatMigrationStore{
+		migrations: newMemoryMap(),
+	}
+}
+
+// Get returns the new chat id of given (migrated) chat id.
+func (s *memoryChatMigrationStore) Get(fromChatID int64) (toChatID int64, exists bool) {
+	var value interface{}
+	if value, exists = s.migrations.get(fromChatID); exists {
+		toChatID = value.(int64)
+	}
+
+	return toChatID, exists
+}
+
+// Set stores the new chat id of given (migrated) chat id.
+func (s *memoryChatMigrationStore) Set(fromChatID, toChatID int64) error {
+	s.migrations.set(fromChatID, toChatID)
+
+	return nil
+}
+
+// ChatMigration is a configuration for handling migrations of groups to supergroups.
+//
+// https://core.telegram.org/bots/api#responseparameters
+type ChatMigration struct {
+	// Store for migrated chat ids. (default: an in-memory one)
+	//
+	// Requests to the migrated chat ids will be sent to the new ones directly.
+	Store ChatMigrationStore
+
+	// If true, a request failed due to migration will be retried once with the new chat id.
+	RetryOnMigrate bool
+
+	// Function which will be called on each migration, for rewriting chat ids in your own storage. (optional)
+	OnMigrate func(b *Bot, fromChatID, toChatID int64)
+}
+
+// SetChatMigration sets the configuration for handling chat migrations. (nil for disabling, which is the default)
+//
+// Migrations are detected from both failed requests (`migrate_to_chat_id` in their response parameters)
+// and received updates (Message.MigrateToChatID and Message.MigrateFromChatID).
+func (b *Bot) SetChatMigration(migration *ChatMigration) {
+	if migration != nil && migration.Store == nil {
+		migration.Store = NewMemoryChatMigrationStore()
+	}
+
+	b.chatMigration = migration
+}
+
+// Record a migration of chat, and call the callback function.
+func (b *Bot) migrateChat(fromChatID, toChatID int64) {
+	if b.chatMigration == nil || fromChatID == toChatID {
+		return
+	}
+
+	if migrated, exists := b.chatMigration.Store.Get(fromChatID); exists && migrated == toChatID {
+		return // already handled
+	}
+
+	b.verbose("chat %d was migrated to %d", fromChatID, toChatID)
+
+	if err := b.chatMigration.Store.Set(fromChatID, toChatID); err != nil {
+		b.error("failed to store migration of chat %d to %d (%s)", fromChatID, toChatID, err)
+	}
+
+	if b.chatMigration.OnMigrate != nil {
+		b.chatMigration.OnMigrate(b, fromChatID, toChatID)
+	}
+}
+
+// Replace `chat_id` of given params with the migrated one, if exists.
+func (b *Bot) replaceMigratedChatID(params map[string]interface{}) {
+	if b.chatMigration == nil {
+		return
+	}
+
+	if fromChatID, ok := chatIDToInt64(params["chat_id"]); ok {
+		if toChatID, exists := b.chatMigration.Store.Get(fromChatID); exists {
+			b.verbose("replacing migrated chat id %d with %d", fromChatID, toChatID)
+
+			params["chat_id"] = toChatID
+		}
+	}
+}
+
+// Check if given response is a failure due to chat migration, and handle it.
+//
+// Returns true if the request should be retried with the new chat id. (`chat_id` of params is already replaced)
+func (b *Bot) handleMigrationFailure(params map[string]interface{}, respBytes []byte, statusCode int) (retry bool) {
+	if b.chatMigration == nil || statusCode != http.StatusBadRequest {
+		return false
+	}
+
+	var base APIResponseBase
+	if json.Unmarshal(respBytes, &base) != nil || base.Parameters == nil || base.Parameters.MigrateToChatID == 0 {
+		return false
+	}
+
+	toChatID := base.Parameters.MigrateToChatID
+	if fromChatID, ok := chatIDToInt64(params["chat_id"]); ok {
+		b.migrateChat(fromChatID, toChatID)
+	}
+
+	if !b.chatMigration.RetryOnMigrate || hasOneTimeFileParam(params) {
+		return false
+	}
+
+	params["chat_id"] = toChatID
+
+	return true
+}
+
+// Check if given update has a migration of chat, and handle it.
+func (b *Bot) handleMigrationUpdate(update Update) {
+	if b.chatMigration == nil || !update.HasMessage() {
+		return
+	}
+
+	message := update.Message
+	if message.HasMigrateToChatID() {
+		b.migrateChat(message.Chat.ID, message.MigrateToChatID)
+	} else if message.HasMigrateFromChatID() {
+		b.migrateChat(message.MigrateFromChatID, message.Chat.ID)
+	}
+}
+
+// Convert given chat id to int64, if it is a numeric one.
+func chatIDToInt64(chatID ChatID) (int64, bool) {
+	switch id := chatID.(type) {
+	case int:
+		return int64(id), true
+	case int64:
+		return id, true
+	case string:
+		if converted, err := strconv.ParseInt(id, 10, 64); err == nil {
+			return converted, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,183 @@
+package telegrambot
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OffsetStore is an interface for storing the offset of updates retrieved with polling.
+//
+// Polling resumes from the stored offset after restarts, so handled updates are not received again
+// and unhandled ones are not skipped.
+type OffsetStore interface {
+	// Load returns the stored offset. (0 if nothing was stored yet)
+	Load() (offset int, err error)
+
+	// Save stores given offset.
+	Save(offset int) error
+}
+
+// in-memory implementation of OffsetStore
+type memoryOffsetStore struct {
+	mutex  sync.RWMutex
+	offset int
+}
+
+// NewMemoryOffsetStore returns a new in-memory OffsetStore.
+func NewMemoryOffsetStore() OffsetStore {
+	return &memoryOffsetStore{}
+}
+
+// Load returns the stored offset.
+func (s *memoryOffsetStore) Load() (offset int, err error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.offset, nil
+}
+
+// Save stores given offset.
+func (s *memoryOffsetStore) Save(offset int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.offset = offset
+
+	return nil
+}
+
+// file-based implementation of OffsetStore
+type fileOffsetStore struct {
+	mutex    sync.Mutex
+	filepath string
+}
+
+// NewFileOffsetStore returns a new OffsetStore which stores the offset in given file.
+func NewFileOffsetStore(filepath string) OffsetStore {
+	return &fileOffsetStore{
+		filepath: filepath,
+	}
+}
+
+// Load returns the offset stored in the file. (0 if the file does not exist yet)
+func (s *fileOffsetStore) Load() (offset int, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var bytes []byte
+	if bytes, err = ioutil.ReadFile(s.filepath); err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(bytes)))
+}
+
+// Save stores given offset in the file.
+func (s *fileOffsetStore) Save(offset int) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return writeFileAtomically(s.filepath, []byte(strconv.Itoa(offset)))
+}
+
+// SetOffsetStore sets the store for persisting the offset of updates retrieved with polling. (nil for disabling, which is the default)
+//
+// When set, the stored offset is loaded when polling starts (if it is greater than the given one),
+// and is saved only after the handlers of all preceding updates complete. (at-least-once delivery across restarts)
+// The next batch of updates is retrieved after the handlers of the current batch complete,
+// so that unhandled updates are not confirmed to the server.
+func (b *Bot) SetOffsetStore(store OffsetStore) {
+	b.offsetStore = store
+}
+
+// for tracking handled updates, and saving the offset of contiguously handled ones
+type offsetTracker struct {
+	bot   *Bot
+	store OffsetStore
+
+	mutex    sync.Mutex
+	pending  map[int]bool // dispatched, but not handled yet (true if it could not be handled)
+	next     int          // offset after the last dispatched update
+	saved    int          // last saved offset
+	handlers sync.WaitGroup
+}
+
+// Create a new offset tracker with given store and offset.
+func newOffsetTracker(b *Bot, store OffsetStore, offset int) *offsetTracker {
+	return &offsetTracker{
+		bot:     b,
+		store:   store,
+		pending: map[int]bool{},
+		next:    offset,
+		saved:   offset,
+	}
+}
+
+// Start tracking given update, and return a function to be called when it is handled.
+func (t *offsetTracker) track(updateID int) (done func(handled bool)) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.pending[updateID] = false
+	if t.next <= updateID {
+		t.next = updateID + 1
+	}
+	t.handlers.Add(1)
+
+	return func(handled bool) {
+		t.complete(updateID, handled)
+	}
+}
+
+// Mark given update as handled, and save the offset if it advanced.
+//
+// Updates which could not be handled are kept pending, so the offset will not advance beyond them.
+func (t *offsetTracker) complete(updateID int, handled bool) {
+	defer t.handlers.Done()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !handled {
+		t.pending[updateID] = true
+		return
+	}
+	delete(t.pending, updateID)
+
+	offset := t.next
+	for id := range t.pending {
+		if id < offset {
+			offset = id
+		}
+	}
+
+	if offset > t.saved {
+		if err := t.store.Save(offset); err != nil {
+			t.bot.error("failed to save offset %d (%s)", offset, err)
+			return
+		}
+		t.saved = offset
+	}
+}
+
+// Wait for all tracked updates to be handled, or until given context is done.
+func (t *offsetTracker) wait(ctx context.Context) {
+	handled := make(chan struct{})
+	go func() {
+		t.handlers.Wait()
+		close(handled)
+	}()
+
+	select {
+	case <-handled:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,276 @@
+package telegrambot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter is an interface for pacing outgoing messages.
+//
+// Implement this interface for sharing limits between multiple processes (eg. with Redis).
+type RateLimiter interface {
+	// Wait blocks until a message can be sent to given chat, or given context is done.
+	Wait(ctx context.Context, chatID ChatID) error
+
+	// Allow reports whether a message can be sent to given chat right now, without blocking.
+	// (the quota is consumed only when it returns true)
+	Allow(chatID ChatID) bool
+}
+
+// RateLimitMode is a mode of applying RateLimiter to requests
+type RateLimitMode int
+
+// RateLimitMode constants
+const (
+	RateLimitModeWait RateLimitMode = iota // block requests until they are allowed
+	RateLimitModeTry                       // fail requests immediately with *RateLimitError when they are not allowed
+)
+
+// RateLimit is a limit of Count messages per given duration. (no limit when Count <= 0)
+type RateLimit struct {
+	Count int
+	Per   time.Duration
+}
+
+// RateLimits is a set of limits for NewRateLimiter().
+type RateLimits struct {
+	Global      RateLimit // for all chats
+	PrivateChat RateLimit // for each private chat
+	GroupChat   RateLimit // for each group, supergroup, or channel
+}
+
+// DefaultRateLimits returns the limits recommended by Telegram.
+//
+// https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this
+func DefaultRateLimits() RateLimits {
+	return RateLimits{
+		Global:      RateLimit{Count: 30, Per: time.Second},
+		PrivateChat: RateLimit{Count: 1, Per: time.Second},
+		GroupChat:   RateLimit{Count: 20, Per: time.Minute},
+	}
+}
+
+// number of per-chat buckets to keep before removing idle ones
+const maxIdleRateLimitBuckets = 1024
+
+// token bucket for a rate limit
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// in-memory implementation of RateLimiter
+type rateLimiter struct {
+	limits RateLimits
+
+	mutex  sync.Mutex
+	global *tokenBucket
+	chats  map[string]*tokenBucket
+}
+
+// NewRateLimiter returns a new in-memory RateLimiter with given limits.
+func NewRateLimiter(limits RateLimits) RateLimiter {
+	return &rateLimiter{
+		limits: limits,
+		chats:  map[string]*tokenBucket{},
+	}
+}
+
+// Wait blocks until a message can be sent to given chat, or given context is done.
+func (l *rateLimiter) Wait(ctx context.Context, chatID ChatID) error {
+	l.mutex.Lock()
+	now := time.Now()
+	global, chat, chatLimit := l.buckets(now, chatID)
+	wait := l.limits.Global.reserve(global, now)
+	if chatWait := chatLimit.reserve(chat, now); chatWait > wait {
+		wait = chatWait
+	}
+	l.mutex.Unlock()
+
+	if err := sleepContext(ctx, wait); err != nil {
+		// give back the reserved quota
+		l.mutex.Lock()
+		l.limits.Global.cancel(global)
+		chatLimit.cancel(chat)
+		l.mutex.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// Allow reports whether a message can be sent to given chat right now, without blocking.
+func (l *rateLimiter) Allow(chatID ChatID) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	global, chat, chatLimit := l.buckets(now, chatID)
+	if !l.limits.Global.available(global, now) || !chatLimit.available(chat, now) {
+		return false
+	}
+	l.limits.Global.reserve(global, now)
+	chatLimit.reserve(chat, now)
+
+	return true
+}
+
+// Get token buckets (and the limit of chat) for given chat id.
+//
+// NOTE: should be called in a locked state.
+func (l *rateLimiter) buckets(now time.Time, chatID ChatID) (global, chat *tokenBucket, chatLimit RateLimit) {
+	if l.global == nil {
+		l.global = l.limits.Global.newBucket(now)
+	}
+
+	key, private := chatIDKey(chatID)
+	if private {
+		chatLimit = l.limits.PrivateChat
+	} else {
+		chatLimit = l.limits.GroupChat
+	}
+
+	var exists bool
+	if chat, exists = l.chats[key]; !exists {
+		if len(l.chats) >= maxIdleRateLimitBuckets {
+			l.removeIdleBuckets(now)
+		}
+
+		chat = chatLimit.newBucket(now)
+		l.chats[key] = chat
+	}
+
+	return l.global, chat, chatLimit
+}
+
+// Remove buckets which are fully refilled.
+//
+// NOTE: should be called in a locked state.
+func (l *rateLimiter) removeIdleBuckets(now time.Time) {
+	idle := l.limits.PrivateChat.Per
+	if l.limits.GroupChat.Per > idle {
+		idle = l.limits.GroupChat.Per
+	}
+
+	for key, bucket := range l.chats {
+		if now.Sub(bucket.last) >= idle {
+			delete(l.chats, key)
+		}
+	}
+}
+
+// Generate a new (full) bucket.
+func (r RateLimit) newBucket(now time.Time) *tokenBucket {
+	return &tokenBucket{tokens: float64(r.Count), last: now}
+}
+
+// Refill given bucket.
+func (r RateLimit) refill(bucket *tokenBucket, now time.Time) {
+	if r.Count <= 0 || r.Per <= 0 {
+		return
+	}
+
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens += elapsed.Seconds() * float64(r.Count) / r.Per.Seconds()
+		if bucket.tokens > float64(r.Count) {
+			bucket.tokens = float64(r.Count)
+		}
+		bucket.last = now
+	}
+}
+
+// Check if a token is available in given bucket.
+func (r RateLimit) available(bucket *tokenBucket, now time.Time) bool {
+	if r.Count <= 0 || r.Per <= 0 {
+		return true
+	}
+
+	r.refill(bucket, now)
+
+	return bucket.tokens >= 1
+}
+
+// Take a token from given bucket, and return the duration to wait until the token is actually available.
+func (r RateLimit) reserve(bucket *tokenBucket, now time.Time) time.Duration {
+	if r.Count <= 0 || r.Per <= 0 {
+		return 0
+	}
+
+	r.refill(bucket, now)
+
+	bucket.tokens--
+	if bucket.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-bucket.tokens * float64(r.Per) / float64(r.Count))
+}
+
+// Give back a reserved token to given bucket.
+func (r RateLimit) cancel(bucket *tokenBucket) {
+	if r.Count <= 0 || r.Per <= 0 {
+		return
+	}
+
+	bucket.tokens++
+}
+
+// Get a key string for given chat id, and whether it is a private chat or not.
+//
+// (ids of users are positive, and ids of groups, supergroups and channels are negative)
+func chatIDKey(chatID ChatID) (key string, private bool) {
+	switch id := chatID.(type) {
+	case int:
+		return fmt.Sprintf("%d", id), id > 0
+	case int64:
+		return fmt.Sprintf("%d", id), id > 0
+	case string:
+		return id, !strings.HasPrefix(id, "@") && !strings.HasPrefix(id, "-")
+	default:
+		return fmt.Sprintf("%v", id), false
+	}
+}
+
+// Check if given method sends a message, so it should be rate limited.
+func isRateLimitedMethod(method string) bool {
+	return (strings.HasPrefix(method, "send") && method != "sendChatAction") || method == "forwardMessage"
+}
+
+// SetRateLimiter sets the rate limiter of this bot. (nil for disabling, which is the default)
+//
+// It will be applied to requests which send messages to a chat (eg. sendMessage, sendPhoto, forwardMessage, ...),
+// keyed on their `chat_id` parameters.
+func (b *Bot) SetRateLimiter(limiter RateLimiter, mode RateLimitMode) {
+	b.rateLimiter = limiter
+	b.rateLimitMode = mode
+}
+
+// Apply the rate limiter of this bot to given request.
+func (b *Bot) applyRateLimit(ctx context.Context, method string, params map[string]interface{}) error {
+	if b.rateLimiter == nil || !isRateLimitedMethod(method) {
+		return nil
+	}
+
+	chatID, exists := params["chat_id"]
+	if !exists {
+		return nil
+	}
+
+	if b.rateLimitMode == RateLimitModeTry {
+		if !b.rateLimiter.Allow(chatID) {
+			return &RateLimitError{Method: method, ChatID: chatID}
+		}
+
+		return nil
+	}
+
+	if err := b.rateLimiter.Wait(ctx, chatID); err != nil {
+		return &RequestError{Method: method, Err: err}
+	}
+
+	return nil
+}
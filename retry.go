@@ -0,0 +1,152 @@
+package telegrambot
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy is a policy for retrying failed requests.
+//
+// Requests rejected with flood control errors (http status 429) will be retried after `retry_after` seconds,
+// as they were not processed by the bot API server at all.
+//
+// Requests failed with server errors (http status 5xx) or transport errors will be retried with exponential backoff,
+// but only for idempotent-safe methods (eg. getMe, getChat, deleteMessage, ...),
+// as there is no way to know whether the failed request was processed or not.
+type RetryPolicy struct {
+	// Maximum number of attempts, including the first one. (retry is disabled when <= 1)
+	MaxAttempts int
+
+	// Backoff duration before the first retry of server/transport errors.
+	// It is doubled on each retry (with jitter), up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Overrides the default behavior per method name (eg. "sendMessage"):
+	//
+	// true for retrying on any error (including server/transport errors),
+	// false for never retrying (including flood control errors).
+	Methods map[string]bool
+}
+
+// DefaultRetryPolicy returns a new RetryPolicy with default values.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Methods:        map[string]bool{},
+	}
+}
+
+// methods which can be retried safely on server/transport errors
+var idempotentMethods = map[string]bool{
+	"getUpdates":             true,
+	"getWebhookInfo":         true,
+	"setWebhook":             true,
+	"deleteWebhook":          true,
+	"getMe":                  true,
+	"getFile":                true,
+	"getUserProfilePhotos":   true,
+	"getStickerSet":          true,
+	"getChat":                true,
+	"getChatAdministrators":  true,
+	"getChatMembersCount":    true,
+	"getChatMember":          true,
+	"getGameHighScores":      true,
+	"sendChatAction":         true,
+	"kickChatMember":         true,
+	"unbanChatMember":        true,
+	"restrictChatMember":     true,
+	"promoteChatMember":      true,
+	"leaveChat":              true,
+	"setChatPhoto":           true,
+	"deleteChatPhoto":        true,
+	"setChatTitle":           true,
+	"setChatDescription":     true,
+	"pinChatMessage":         true,
+	"unpinChatMessage":       true,
+	"setChatStickerSet":      true,
+	"deleteChatStickerSet":   true,
+	"editMessageText":        true,
+	"editMessageCaption":     true,
+	"editMessageMedia":       true,
+	"editMessageReplyMarkup": true,
+	"deleteMessage":          true,
+}
+
+// SetRetryPolicy sets the retry policy of this bot. (nil for disabling retries, which is the default)
+func (b *Bot) SetRetryPolicy(policy *RetryPolicy) {
+	b.retryPolicy = policy
+}
+
+// Get the duration to wait before retrying the failed request, or false if it should not be retried.
+//
+// attempt is the number of attempts made so far.
+func (p *RetryPolicy) retryAfter(method string, params map[string]interface{}, attempt int, respBytes []byte, statusCode int, err error) (wait time.Duration, retry bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+
+	override, overridden := p.Methods[method]
+	if overridden && !override {
+		return 0, false
+	}
+
+	// files which were already read cannot be sent again
+	if hasOneTimeFileParam(params) {
+		return 0, false
+	}
+
+	// flood control
+	if err == nil && statusCode == http.StatusTooManyRequests {
+		var base APIResponseBase
+		if json.Unmarshal(respBytes, &base) == nil && base.Parameters != nil && base.Parameters.RetryAfter > 0 {
+			return time.Duration(base.Parameters.RetryAfter) * time.Second, true
+		}
+	}
+
+	// server/transport errors
+	if err != nil || statusCode >= http.StatusInternalServerError {
+		if idempotentMethods[method] || override {
+			return p.backoff(attempt), true
+		}
+	}
+
+	return 0, false
+}
+
+// Get the exponential backoff duration (with jitter) for given attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	// equal jitter: [backoff/2, backoff)
+	half := backoff / 2
+
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+// Sleep for given duration, or until given context is done.
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
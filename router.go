@@ -0,0 +1,225 @@
+package telegrambot
+
+import (
+	"sync"
+)
+
+// MessageHandler is a function type for handling messages (and edited messages, channel posts, edited channel posts).
+type MessageHandler func(b *Bot, update Update, message Message)
+
+// InlineQueryHandler is a function type for handling inline queries.
+type InlineQueryHandler func(b *Bot, update Update, query InlineQuery)
+
+// ChosenInlineResultHandler is a function type for handling chosen inline results.
+type ChosenInlineResultHandler func(b *Bot, update Update, result ChosenInlineResult)
+
+// CallbackQueryHandler is a function type for handling callback queries.
+type CallbackQueryHandler func(b *Bot, update Update, query CallbackQuery)
+
+// ShippingQueryHandler is a function type for handling shipping queries.
+type ShippingQueryHandler func(b *Bot, update Update, query ShippingQuery)
+
+// PreCheckoutQueryHandler is a function type for handling pre-checkout queries.
+type PreCheckoutQueryHandler func(b *Bot, update Update, query PreCheckoutQuery)
+
+// PollHandler is a function type for handling polls.
+type PollHandler func(b *Bot, update Update, poll Poll)
+
+// UpdateRouter routes updates to the handlers registered for their types.
+//
+// Multiple handlers with filters can be registered for each type, and the first one with matching filters will be called.
+// Updates without matching handlers (and errors) are routed to the fallback handler.
+// When there is no fallback handler, they are logged with their types.
+type UpdateRouter struct {
+	mutex          sync.RWMutex
+	commands       *CommandRouter
+	routes         map[UpdateType][]updateRoute
+	fallback       UpdateHandler
+	quietUnhandled bool // do not log unhandled updates
+}
+
+// a handler registered in UpdateRouter
+type updateRoute struct {
+	filter Filter
+	handle func(b *Bot, update Update)
+}
+
+// NewUpdateRouter returns a new UpdateRouter.
+func NewUpdateRouter() *UpdateRouter {
+	return &UpdateRouter{
+		commands: NewCommandRouter(),
+		routes:   map[UpdateType][]updateRoute{},
+	}
+}
+
+// OnCommand registers a handler for given bot command (eg. "/start" or "start"), which will be called when all of given filters match.
+//
+// Messages with registered commands are routed to their command handlers instead of the message handlers.
+func (r *UpdateRouter) OnCommand(command string, handler CommandHandler, filters ...Filter) *UpdateRouter {
+	r.commands.Handle(command, handler, filters...)
+
+	return r
+}
+
+// OnMessage registers a handler for messages, which will be called when all of given filters match.
+func (r *UpdateRouter) OnMessage(handler MessageHandler, filters ...Filter) *UpdateRouter {
+	return r.add(UpdateTypeMessage, filters, func(b *Bot, update Update) {
+		handler(b, update, *update.Message)
+	})
+}
+
+// OnEditedMessage registers a handler for edited messages, which will be called when all of given filters match.
+func (r *UpdateRouter) OnEditedMessage(handler MessageHandler, filters ...Filter) *UpdateRouter {
+	return r.add(UpdateTypeEditedMessage, filters, func(b *Bot, update Update) {
+		handler(b, update, *update.EditedMessage)
+	})
+}
+
+// OnChannelPost registers a handler for channel posts, which will be called when all of given filters match.
+func (r *UpdateRouter) OnChannelPost(handler MessageHandler, filters ...Filter) *UpdateRouter {
+	return r.add(UpdateTypeChannelPost, filters, func(b *Bot, update Update) {
+		handler(b, update, *update.ChannelPost)
+	})
+}
+
+// OnEditedChannelPost registers a handler for edited channel posts, which will be called when all of given filters match.
+func (r *UpdateRouter) OnEditedChannelPost(handler MessageHandler, filters ...Filter) *UpdateRouter {
+	return r.add(UpdateTypeEditedChannelPost, filters, func(b *Bot, update Update) {
+		handler(b, update, *update.EditedChannelPost)
+	})
+}
+
+// OnInlineQuery registers a handler for inline queries, which will be called when all of given filters match.
+func (r *UpdateRouter) OnInlineQuery(handler InlineQueryHandler, filters ...Filter) *UpdateRouter {
+	return r.add(UpdateTypeInlineQuery, filters, func(b *Bot, update Update) {
+		handler(b, update, *update.InlineQuery)
+	})
+}
+
+// OnChosenInlineResult registers a handler for chosen inline results, which will be called when all of given filters match.
+func (r *UpdateRouter) OnChosenInlineResult(handler ChosenInlineResultHandler, filters ...Filter) *UpdateRouter {
+	return r.add(UpdateTypeChosenInlineResult, filters, func(b *Bot, update Update) {
+		handler(b, update, *update.ChosenInlineResult)
+	})
+}
+
+// OnCallbackQuery registers a handler for callback queries, which will be called when all of given filters match.
+func (r *UpdateRouter) OnCallbackQuery(handler CallbackQueryHandler, filters ...Filter) *UpdateRouter {
+	return r.add(UpdateTypeCallbackQuery, filters, func(b *Bot, update Update) {
+		handler(b, update, *update.CallbackQuery)
+	})
+}
+
+// OnShippingQuery registers a handler for shipping queries, which will be called when all of given filters match.
+func (r *UpdateRouter) OnShippingQuery(handler ShippingQueryHandler, filters ...Filter) *UpdateRouter {
+	return r.add(UpdateTypeShippingQuery, filters, func(b *Bot, update Update) {
+		handler(b, update, *update.ShippingQuery)
+	})
+}
+
+// OnPreCheckoutQuery registers a handler for pre-checkout queries, which will be called when all of given filters match.
+func (r *UpdateRouter) OnPreCheckoutQuery(handler PreCheckoutQueryHandler, filters ...Filter) *UpdateRouter {
+	return r.add(UpdateTypePreCheckoutQuery, filters, func(b *Bot, update Update) {
+		handler(b, update, *update.PreCheckoutQuery)
+	})
+}
+
+// OnPoll registers a handler for polls, which will be called when all of given filters match.
+func (r *UpdateRouter) OnPoll(handler PollHandler, filters ...Filter) *UpdateRouter {
+	return r.add(UpdateTypePoll, filters, func(b *Bot, update Update) {
+		handler(b, update, *update.Poll)
+	})
+}
+
+// OnFallback registers a handler for updates which have no matching handlers, and errors.
+func (r *UpdateRouter) OnFallback(handler UpdateHandler) *UpdateRouter {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.fallback = handler
+
+	return r
+}
+
+// SetLogUnhandled sets whether to log updates without matching handlers (with their types) or not. (default: true)
+func (r *UpdateRouter) SetLogUnhandled(enabled bool) *UpdateRouter {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.quietUnhandled = !enabled
+
+	return r
+}
+
+// Add a handler for given update type with filters.
+func (r *UpdateRouter) add(updateType UpdateType, filters []Filter, handle func(b *Bot, update Update)) *UpdateRouter {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.routes[updateType] = append(r.routes[updateType], updateRoute{
+		filter: allFilters(filters),
+		handle: handle,
+	})
+
+	return r
+}
+
+// HandleUpdate routes given update to the handler registered for its type.
+//
+// It can be used as an update handler, eg. `client.StartLongPollingUpdates(0, 50, router.HandleUpdate)`.
+func (r *UpdateRouter) HandleUpdate(b *Bot, update Update, err error) {
+	if err != nil {
+		r.mutex.RLock()
+		fallback := r.fallback
+		r.mutex.RUnlock()
+
+		if fallback != nil {
+			fallback(b, update, err)
+		} else {
+			b.error("error while receiving update (%s)", err)
+		}
+
+		return
+	}
+
+	r.Route(b, update)
+}
+
+// Route routes given update to the first matching handler registered for its type, and returns whether it was handled or not.
+func (r *UpdateRouter) Route(b *Bot, update Update) (handled bool) {
+	updateType := update.Type()
+
+	// commands first
+	if updateType == UpdateTypeMessage || updateType == UpdateTypeChannelPost {
+		if r.commands.Route(b, update) {
+			return true
+		}
+	}
+
+	r.mutex.RLock()
+	routes := r.routes[updateType]
+	fallback := r.fallback
+	quietUnhandled := r.quietUnhandled
+	r.mutex.RUnlock()
+
+	for _, route := range routes {
+		if route.filter(b, update) {
+			route.handle(b, update)
+			return true
+		}
+	}
+
+	if fallback != nil {
+		fallback(b, update, nil)
+		return true
+	}
+
+	if !quietUnhandled {
+		if updateType == "" {
+			updateType = "unknown"
+		}
+		b.info("unhandled update (type: %s, id: %d)", updateType, update.UpdateID)
+	}
+
+	return false
+}
@@ -14,8 +14,8 @@ import (
 const (
 	apiToken = "01234567:abcdefghijklmn_ABCDEFGHIJKLMNOPQRST"
 
-	monitorIntervalSeconds = 1
-	typingDelaySeconds     = 1
+	longPollingTimeoutSeconds = 50
+	typingDelaySeconds        = 1
 
 	verbose = true
 )
@@ -140,9 +140,9 @@ func main() {
 		// delete webhook (getting updates will not work when wehbook is set up)
 		if unhooked := client.DeleteWebhook(); unhooked.Ok {
 			// wait for new updates
-			client.StartMonitoringUpdates(
+			client.StartLongPollingUpdates(
 				0,
-				monitorIntervalSeconds,
+				longPollingTimeoutSeconds,
 				handleUpdate,
 			)
 		} else {
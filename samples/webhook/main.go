@@ -159,11 +159,13 @@ func main() {
 					certFilepath,
 				); hooked.Ok {
 					// on success, start webhook server
-					client.StartWebhookServerAndWait(
+					if err := client.StartWebhookServerAndWait(
 						certFilepath,
 						keyFilepath,
 						handleWebhook,
-					)
+					); err != nil {
+						panic("failed to start webhook server: " + err.Error())
+					}
 				} else {
 					panic("failed to set webhook")
 				}
@@ -0,0 +1,210 @@
+package telegrambot
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// an in-memory map for stores, which is safe for concurrent use
+type memoryMap struct {
+	mutex  sync.RWMutex
+	values map[interface{}]interface{}
+}
+
+// Create a new in-memory map.
+func newMemoryMap() *memoryMap {
+	return &memoryMap{
+		values: map[interface{}]interface{}{},
+	}
+}
+
+// Get the value of given key.
+func (m *memoryMap) get(key interface{}) (value interface{}, exists bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	value, exists = m.values[key]
+
+	return value, exists
+}
+
+// Set the value of given key.
+func (m *memoryMap) set(key, value interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.values[key] = value
+}
+
+// Delete the value of given key.
+func (m *memoryMap) delete(key interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.values, key)
+}
+
+// Get all keys.
+func (m *memoryMap) keys() (keys []interface{}) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	keys = make([]interface{}, 0, len(m.values))
+	for key := range m.values {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// a map for stores, which is kept in a file as a JSON object
+//
+// The file is loaded lazily, and is saved with writeFileAtomically on each change.
+type jsonFileMap struct {
+	mutex    sync.Mutex
+	filepath string
+	values   map[string]json.RawMessage // loaded lazily
+}
+
+// Create a new map which is kept in given file.
+func newJSONFileMap(filepath string) *jsonFileMap {
+	return &jsonFileMap{
+		filepath: filepath,
+	}
+}
+
+// Get the value of given key, and decode it into given pointer.
+func (m *jsonFileMap) get(key string, value interface{}) (exists bool, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err = m.load(); err != nil {
+		return false, err
+	}
+
+	var raw json.RawMessage
+	if raw, exists = m.values[key]; !exists {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(raw, value)
+}
+
+// Set the value of given key, and save it to the file.
+func (m *jsonFileMap) set(key string, value interface{}) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.load(); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	m.values[key] = raw
+
+	return m.save()
+}
+
+// Delete the value of given key, and save it to the file.
+func (m *jsonFileMap) delete(key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.load(); err != nil {
+		return err
+	}
+
+	if _, exists := m.values[key]; !exists {
+		return nil
+	}
+	delete(m.values, key)
+
+	return m.save()
+}
+
+// Get all keys.
+func (m *jsonFileMap) keys() (keys []string, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err = m.load(); err != nil {
+		return nil, err
+	}
+
+	keys = make([]string, 0, len(m.values))
+	for key := range m.values {
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// Load values from the file, if not loaded yet.
+func (m *jsonFileMap) load() error {
+	if m.values != nil {
+		return nil
+	}
+
+	values := map[string]json.RawMessage{}
+	if bytes, err := ioutil.ReadFile(m.filepath); err == nil {
+		if err = json.Unmarshal(bytes, &values); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	m.values = values
+
+	return nil
+}
+
+// Save values to the file.
+func (m *jsonFileMap) save() error {
+	bytes, err := json.Marshal(m.values)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(m.filepath, bytes)
+}
+
+// Write given bytes to a temporary file, and rename it to given filepath, so that the file will not be corrupted on crashes.
+func writeFileAtomically(path string, bytes []byte) (err error) {
+	return writeFileAtomicallyWith(path, 0600, func(w io.Writer) error {
+		_, err := w.Write(bytes)
+		return err
+	})
+}
+
+// Write contents with given function to a temporary file with given permission, and rename it to given filepath.
+//
+// The temporary file is removed when the function fails.
+func writeFileAtomicallyWith(path string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	var file *os.File
+	if file, err = ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp"); err != nil {
+		return err
+	}
+	defer os.Remove(file.Name()) // no-op after successful rename
+
+	if err = file.Chmod(perm); err == nil {
+		err = write(file)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
@@ -2,6 +2,10 @@ package telegrambot
 
 // https://core.telegram.org/bots/api#available-types
 
+import (
+	"io"
+)
+
 // ChatID can be `Message.Chat.Id`,
 // or target channel name (in string, eg. "@channelusername")
 type ChatID interface{}
@@ -11,9 +15,10 @@ type ChatType string
 
 // ChatType strings
 const (
-	ChatTypePrivate ChatType = "private"
-	ChatTypeGroup   ChatType = "group"
-	ChatTypeChannel ChatType = "channel"
+	ChatTypePrivate    ChatType = "private"
+	ChatTypeGroup      ChatType = "group"
+	ChatTypeSupergroup ChatType = "supergroup"
+	ChatTypeChannel    ChatType = "channel"
 )
 
 // ParseMode is a mode of parse
@@ -114,8 +119,12 @@ const (
 // APIResponseBase is a base of API responses
 type APIResponseBase struct {
 	Ok          bool                   `json:"ok"`
+	ErrorCode   int                    `json:"error_code,omitempty"`
 	Description *string                `json:"description,omitempty"`
 	Parameters  *APIResponseParameters `json:"parameters,omitempty"`
+
+	statusCode int   // http status code of the response
+	err        error // error while sending the request or handling its response
 }
 
 // APIResponseParameters is parameters in API responses
@@ -343,8 +352,8 @@ const (
 // https://core.telegram.org/bots/api#inputmedia
 type InputMedia struct {
 	Type              InputMediaType `json:"type"`
-	Media             string         `json:"media"`
-	Thumb             InputFile      `json:"thumb,omitempty"` // video, animation, audio, document
+	Media             InputFile      `json:"media"`
+	Thumb             *InputFile     `json:"thumb,omitempty"` // video, animation, audio, document
 	Caption           string         `json:"caption,omitempty"`
 	ParseMode         ParseMode      `json:"parse_mode,omitempty"`
 	Width             int            `json:"width,omitempty"`              // video, animation
@@ -364,6 +373,14 @@ type InputFile struct {
 	URL      *string
 	Bytes    []byte
 	FileID   *string
+
+	Reader io.Reader // will be streamed, so it cannot be retried
+	Size   int64     // size of Reader in bytes (0 or -1 if unknown)
+
+	Filename    string // filename to be uploaded with (optional for Filepath and Bytes)
+	ContentType string // MIME type to be uploaded with (optional, detected from contents or filename if not given)
+
+	attachName string // name of multipart part, when attached to InputMedia with `attach://<name>`
 }
 
 // Audio is a struct for an audio file
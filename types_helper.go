@@ -10,6 +10,11 @@ import (
 ////////////////////////////////
 // Helper functions for InlineQueryResult
 
+// Get the id of InlineQueryResult. (also promoted to the structs which embed it)
+func (r InlineQueryResult) getID() string {
+	return r.ID
+}
+
 // Generate a random UUID according to RFC-4122
 //
 // http://play.golang.org/p/4FkNSiUDMg
@@ -380,6 +385,35 @@ func NewInlineQueryResultCachedAudio(audioFileID string) (newAudio *InlineQueryR
 	return &InlineQueryResultCachedAudio{}, nil
 }
 
+////////////////////////////////
+// Helper functions for APIResponseBase
+//
+
+// Err returns the error of an API response, or nil if it was successful.
+//
+// It will be an *APIError when the bot API server returned an error,
+// or a *RequestError when the request could not be sent or its response could not be handled.
+func (r APIResponseBase) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.Ok {
+		return nil
+	}
+
+	var description string
+	if r.Description != nil {
+		description = *r.Description
+	}
+
+	return &APIError{
+		StatusCode:  r.statusCode,
+		ErrorCode:   r.ErrorCode,
+		Description: description,
+		Parameters:  r.Parameters,
+	}
+}
+
 ////////////////////////////////
 // Helper functions for Update
 //
@@ -389,6 +423,34 @@ func (u Update) String() string {
 	return structToString(u)
 }
 
+// Type returns the UpdateType of Update. (empty if it is not known)
+func (u *Update) Type() UpdateType {
+	switch {
+	case u.Message != nil:
+		return UpdateTypeMessage
+	case u.EditedMessage != nil:
+		return UpdateTypeEditedMessage
+	case u.ChannelPost != nil:
+		return UpdateTypeChannelPost
+	case u.EditedChannelPost != nil:
+		return UpdateTypeEditedChannelPost
+	case u.InlineQuery != nil:
+		return UpdateTypeInlineQuery
+	case u.ChosenInlineResult != nil:
+		return UpdateTypeChosenInlineResult
+	case u.CallbackQuery != nil:
+		return UpdateTypeCallbackQuery
+	case u.ShippingQuery != nil:
+		return UpdateTypeShippingQuery
+	case u.PreCheckoutQuery != nil:
+		return UpdateTypePreCheckoutQuery
+	case u.Poll != nil:
+		return UpdateTypePoll
+	}
+
+	return ""
+}
+
 // HasMessage checks if Update has Message.
 func (u *Update) HasMessage() bool {
 	return u.Message != nil
@@ -608,12 +670,12 @@ func (m *Message) HasChannelChatCreated() bool {
 
 // HasMigrateToChatID checks if Message has MigrateToChatId.
 func (m *Message) HasMigrateToChatID() bool {
-	return m.MigrateToChatID > 0
+	return m.MigrateToChatID != 0 // ids of supergroups are negative
 }
 
 // HasMigrateFromChatID checks if Message has MigrateFromChatId.
 func (m *Message) HasMigrateFromChatID() bool {
-	return m.MigrateFromChatID > 0
+	return m.MigrateFromChatID != 0 // ids of groups are negative
 }
 
 // HasPinnedMessage checks if Message has PinnedMessage.
@@ -771,9 +833,67 @@ func InputFileFromBytes(bytes []byte) InputFile {
 	}
 }
 
+// InputFileFromReader generates an InputFile from given reader, filename, and size in bytes (0 or -1 if unknown)
+//
+// The reader will be streamed while uploading, so requests with it will not be retried.
+func InputFileFromReader(reader io.Reader, filename string, size int64) InputFile {
+	return InputFile{
+		Reader:   reader,
+		Filename: filename,
+		Size:     size,
+	}
+}
+
+// InputFileFromReaderWithContentType generates an InputFile from given reader, filename, MIME type, and size in bytes (0 or -1 if unknown)
+//
+// The reader will be streamed while uploading, so requests with it will not be retried.
+func InputFileFromReaderWithContentType(reader io.Reader, filename, contentType string, size int64) InputFile {
+	return InputFile{
+		Reader:      reader,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+	}
+}
+
 // InputFileFromFileID generates an InputFile from given file id
 func InputFileFromFileID(fileID string) InputFile {
 	return InputFile{
 		FileID: &fileID,
 	}
 }
+
+// SetFilename sets the filename of InputFile to be uploaded with.
+func (f InputFile) SetFilename(filename string) InputFile {
+	f.Filename = filename
+	return f
+}
+
+// SetContentType sets the MIME type of InputFile to be uploaded with.
+func (f InputFile) SetContentType(contentType string) InputFile {
+	f.ContentType = contentType
+	return f
+}
+
+// Check if InputFile has contents to be uploaded. (not an URL or file id)
+func (f InputFile) isUploadable() bool {
+	return f.Filepath != nil || len(f.Bytes) > 0 || f.Reader != nil
+}
+
+// MarshalJSON encodes InputFile as a string for JSON params. (eg. `media` and `thumb` of InputMedia)
+//
+// Contents to be uploaded are referenced with `attach://<name>`, so they must be attached to the request first.
+func (f InputFile) MarshalJSON() ([]byte, error) {
+	switch {
+	case f.attachName != "":
+		return json.Marshal("attach://" + f.attachName)
+	case f.URL != nil:
+		return json.Marshal(*f.URL)
+	case f.FileID != nil:
+		return json.Marshal(*f.FileID)
+	case f.isUploadable():
+		return nil, fmt.Errorf("InputFile with contents to be uploaded cannot be encoded as json without being attached")
+	}
+
+	return []byte("null"), nil
+}
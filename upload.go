@@ -0,0 +1,314 @@
+package telegrambot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// UploadProgressFunc is a function type for reporting progress of uploads.
+//
+// `total` is the size of the whole request body in bytes. (-1 if unknown)
+type UploadProgressFunc func(sent, total int64)
+
+// context key for UploadProgressFunc
+type uploadProgressKey struct{}
+
+// WithUploadProgress returns a copy of given context with an upload progress callback,
+// which will be called while the files of requests (with the returned context) are uploaded.
+//
+// eg. `client.SendDocumentContext(WithUploadProgress(ctx, fn), chatID, document, options)`
+func WithUploadProgress(ctx context.Context, progress UploadProgressFunc) context.Context {
+	return context.WithValue(ctx, uploadProgressKey{}, progress)
+}
+
+// Get the upload progress callback of given context. (nil if none)
+func uploadProgress(ctx context.Context) UploadProgressFunc {
+	if progress, ok := ctx.Value(uploadProgressKey{}).(UploadProgressFunc); ok {
+		return progress
+	}
+
+	return nil
+}
+
+// a reader which reports the number of bytes read
+type progressReader struct {
+	reader   io.Reader
+	sent     int64
+	total    int64
+	progress UploadProgressFunc
+}
+
+// Read reads from the underlying reader, and reports the progress.
+func (r *progressReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	if n > 0 {
+		r.progress(atomic.AddInt64(&r.sent, int64(n)), r.total)
+	}
+
+	return n, err
+}
+
+// a writer which only counts the number of bytes written
+type countingWriter struct {
+	count int64
+}
+
+// Write counts the number of given bytes.
+func (w *countingWriter) Write(p []byte) (n int, err error) {
+	w.count += int64(len(p))
+
+	return len(p), nil
+}
+
+// a part of multipart form data
+type multipartPart struct {
+	key   string
+	value string // for fields
+
+	isFile      bool
+	filename    string
+	contentType string
+	filepath    string    // file to be opened lazily (when reader is nil)
+	reader      io.Reader // contents of file
+	size        int64     // -1 if unknown
+}
+
+// Convert given params into parts of multipart form data. (sorted by keys, for computing the content length)
+//
+// Files will not be read until the parts are written.
+func (b *Bot) multipartParts(params map[string]interface{}) (parts []multipartPart, err error) {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts = []multipartPart{}
+	for _, key := range keys {
+		value := params[key]
+
+		switch v := value.(type) {
+		case *os.File:
+			size := int64(-1)
+			if info, err := v.Stat(); err == nil && info.Mode().IsRegular() {
+				if offset, err := v.Seek(0, io.SeekCurrent); err == nil {
+					size = info.Size() - offset
+				}
+			}
+
+			parts = append(parts, multipartPart{key: key, isFile: true, filename: v.Name(), contentType: contentTypeFromFilename(v.Name()), reader: v, size: size})
+		case []byte:
+			contentType := http.DetectContentType(v)
+			filename := fmt.Sprintf("%s.%s", key, getExtension(contentType))
+
+			parts = append(parts, multipartPart{key: key, isFile: true, filename: filename, contentType: contentType, reader: bytes.NewReader(v), size: int64(len(v))})
+		case InputFile:
+			part := multipartPart{key: key, isFile: true, filename: v.Filename, contentType: v.ContentType}
+
+			if v.Filepath != nil {
+				var info os.FileInfo
+				if info, err = os.Stat(*v.Filepath); err != nil {
+					return nil, fmt.Errorf("parameter '%s' could not be read from file: %s", key, err)
+				}
+
+				part.filepath, part.size = *v.Filepath, info.Size()
+				if part.filename == "" {
+					part.filename = *v.Filepath
+				}
+				if part.contentType == "" {
+					part.contentType = contentTypeFromFilename(part.filename)
+				}
+			} else if len(v.Bytes) > 0 {
+				part.reader, part.size = bytes.NewReader(v.Bytes), int64(len(v.Bytes))
+				if part.contentType == "" {
+					part.contentType = http.DetectContentType(v.Bytes)
+				}
+				if part.filename == "" {
+					part.filename = fmt.Sprintf("%s.%s", key, getExtension(part.contentType))
+				}
+			} else if v.Reader != nil {
+				part.reader, part.size = v.Reader, v.Size
+				if part.size <= 0 { // unknown (or zero value of Size), so it will be streamed without content length
+					part.size = -1
+				}
+				if part.filename == "" {
+					part.filename = key
+				}
+				if part.contentType == "" {
+					part.contentType = contentTypeFromFilename(part.filename)
+				}
+			} else if strValue, ok := b.paramToString(value); ok {
+				part = multipartPart{key: key, value: strValue}
+			} else {
+				return nil, fmt.Errorf("invalid InputFile parameter '%s'", key)
+			}
+
+			parts = append(parts, part)
+		default:
+			if strValue, ok := b.paramToString(value); ok {
+				parts = append(parts, multipartPart{key: key, value: strValue})
+			}
+		}
+	}
+
+	return parts, nil
+}
+
+// Attach uploadable media and thumbnails of given InputMedia to params, and return copies of them
+// which reference the attached files with `attach://<name>`.
+//
+// URLs and file ids are left as they are.
+func attachInputMedia(params map[string]interface{}, media ...InputMedia) []InputMedia {
+	attached := make([]InputMedia, len(media))
+	for i, m := range media {
+		if m.Media.isUploadable() {
+			name := fmt.Sprintf("file%d", i)
+			params[name] = m.Media
+			m.Media.attachName = name
+		}
+		if m.Thumb != nil && m.Thumb.isUploadable() {
+			name := fmt.Sprintf("thumb%d", i)
+			params[name] = *m.Thumb
+			thumb := *m.Thumb
+			thumb.attachName = name
+			m.Thumb = &thumb
+		}
+
+		attached[i] = m
+	}
+
+	return attached
+}
+
+// Compute the content length of multipart form data with given parts and boundary. (-1 if unknown)
+func multipartContentLength(parts []multipartPart, boundary string) int64 {
+	counter := &countingWriter{}
+	writer := multipart.NewWriter(counter)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return -1
+	}
+
+	for _, part := range parts {
+		if part.isFile {
+			if part.size < 0 {
+				return -1
+			}
+			if _, err := createFilePart(writer, part); err != nil {
+				return -1
+			}
+			counter.count += part.size
+		} else if err := writer.WriteField(part.key, part.value); err != nil {
+			return -1
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return -1
+	}
+
+	return counter.count
+}
+
+// Write given parts with the multipart writer, and close it.
+func writeMultipartParts(writer *multipart.Writer, parts []multipartPart) (err error) {
+	for _, part := range parts {
+		if part.isFile {
+			var w io.Writer
+			if w, err = createFilePart(writer, part); err != nil {
+				return fmt.Errorf("could not create form file for parameter '%s' (%s)", part.key, err)
+			}
+
+			reader := part.reader
+			if reader == nil { // open the file lazily
+				var file *os.File
+				if file, err = os.Open(part.filepath); err != nil {
+					return fmt.Errorf("parameter '%s' could not be read from file: %s", part.key, err)
+				}
+				reader = file
+			}
+
+			_, err = io.Copy(w, reader)
+			if part.reader == nil {
+				reader.(*os.File).Close()
+			}
+			if err != nil {
+				return fmt.Errorf("could not write to multipart: %s (%s)", part.key, err)
+			}
+		} else if err = writer.WriteField(part.key, part.value); err != nil {
+			return fmt.Errorf("could not write field to multipart: %s (%s)", part.key, err)
+		}
+	}
+
+	return writer.Close()
+}
+
+// Create a part of file with its filename and content type.
+func createFilePart(writer *multipart.Writer, part multipartPart) (io.Writer, error) {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(part.key), escapeQuotes(filepath.Base(part.filename))))
+	header.Set("Content-Type", part.contentType)
+
+	return writer.CreatePart(header)
+}
+
+// Escape quotes and backslashes in given string. (for Content-Disposition header)
+func escapeQuotes(str string) string {
+	return strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace(str)
+}
+
+// Get the MIME type from the extension of given filename. ("application/octet-stream" if not known)
+func contentTypeFromFilename(filename string) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(filename)); contentType != "" {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
+
+// Build a streaming body of multipart form data with given params.
+//
+// Returned function should be called after the request is finished, for stopping the writer and releasing files.
+func (b *Bot) multipartBody(ctx context.Context, params map[string]interface{}) (body io.Reader, contentType string, contentLength int64, closeBody func(), err error) {
+	var parts []multipartPart
+	if parts, err = b.multipartParts(params); err != nil {
+		return nil, "", 0, nil, err
+	}
+
+	reader, writer := io.Pipe()
+	multipartWriter := multipart.NewWriter(writer)
+	contentType = multipartWriter.FormDataContentType()
+	contentLength = multipartContentLength(parts, multipartWriter.Boundary())
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+
+		writer.CloseWithError(writeMultipartParts(multipartWriter, parts))
+	}()
+
+	body = reader
+	if progress := uploadProgress(ctx); progress != nil {
+		body = &progressReader{
+			reader:   reader,
+			total:    contentLength,
+			progress: progress,
+		}
+	}
+
+	closeBody = func() {
+		reader.Close() // stop the writer, if the body was not read to the end
+		<-written
+	}
+
+	return body, contentType, contentLength, closeBody, nil
+}
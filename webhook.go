@@ -0,0 +1,264 @@
+package telegrambot
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebhookServer is a handle of a running webhook server.
+type WebhookServer struct {
+	bot    *Bot
+	server *http.Server
+
+	handlers sync.WaitGroup // running (or queued) update handlers
+
+	stopped chan struct{} // closed when the server stops
+	err     error         // error which stopped the server
+}
+
+// StartWebhookServer starts a webhook server in background, and returns its handle.
+// Function SetWebhook(host, port, certFilepath) should be called priorly to setup host, port, and certification file.
+// Certification file(.pem) and a private key is needed.
+// Incoming webhooks will be received through webhookHandler function.
+//
+// Returns an error if the server could not be started. (eg. invalid certificate, or port already in use)
+//
+// https://core.telegram.org/bots/self-signed
+func (b *Bot) StartWebhookServer(certFilepath string, keyFilepath string, webhookHandler func(b *Bot, webhook Update, err error)) (*WebhookServer, error) {
+	b.verbose("starting webhook server on: %s (port: %d) ...", b.getWebhookPath(), b.webhookPort)
+
+	// set update handler
+	if webhookHandler == nil {
+		return nil, fmt.Errorf("given webhook handler is nil")
+	}
+	b.updateHandler = webhookHandler
+
+	cert, err := tls.LoadX509KeyPair(certFilepath, keyFilepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %s", err)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", b.webhookPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on port %d: %s", b.webhookPort, err)
+	}
+
+	s := &WebhookServer{
+		bot:     b,
+		stopped: make(chan struct{}),
+	}
+
+	// routing
+	mux := http.NewServeMux()
+	mux.HandleFunc(b.getWebhookPath(), func(writer http.ResponseWriter, req *http.Request) {
+		b.handleWebhook(writer, req, webhookHandler, &s.handlers)
+	})
+
+	// start server
+	s.server = &http.Server{
+		Handler:           mux,
+		TLSConfig:         &tls.Config{Certificates: []tls.Certificate{cert}},
+		ReadTimeout:       10 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+	go func() {
+		if err := s.server.ServeTLS(listener, "", ""); err != http.ErrServerClosed {
+			b.error("webhook server stopped with error: %s", err)
+
+			s.err = err
+		}
+
+		close(s.stopped)
+	}()
+
+	return s, nil
+}
+
+// Wait blocks until the webhook server stops.
+//
+// Returns the error which stopped the server, or nil if it was shut down with Shutdown().
+func (s *WebhookServer) Wait() error {
+	<-s.stopped
+
+	return s.err
+}
+
+// Shutdown stops the webhook server gracefully.
+//
+// It stops accepting new requests, and waits for in-flight requests and their update handlers
+// (including the ones queued in the update dispatcher) to finish, or until given context is done.
+func (s *WebhookServer) Shutdown(ctx context.Context) error {
+	s.bot.verbose("shutting down webhook server...")
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	// wait for running update handlers
+	finished := make(chan struct{})
+	go func() {
+		s.handlers.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	<-s.stopped
+
+	s.bot.verbose("webhook server was shut down")
+
+	return nil
+}
+
+// WebhookHandler returns an http.Handler for receiving webhook updates through given webhookHandler function.
+//
+// It can be mounted on any http server at any path, and also be served with plain HTTP behind a TLS-terminating proxy.
+// (in that case, the proxy's public url should be set with SetWebhookURL())
+//
+// It responds with:
+//
+// 405 for non-POST requests, 400 for malformed updates, and 200 after the update is accepted.
+func (b *Bot) WebhookHandler(webhookHandler func(b *Bot, webhook Update, err error)) http.Handler {
+	if webhookHandler == nil {
+		b.error("given webhook handler is nil")
+
+		webhookHandler = func(b *Bot, webhook Update, err error) {}
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		b.handleWebhook(writer, req, webhookHandler, nil)
+	})
+}
+
+// TelegramWebhookNetworks is a list of networks from which Telegram sends webhook requests.
+//
+// https://core.telegram.org/bots/webhooks#the-short-version
+var TelegramWebhookNetworks = []string{
+	"149.154.160.0/20",
+	"91.108.4.0/22",
+}
+
+// header name of webhook secret token
+const webhookSecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// SetWebhookSecretToken sets the secret token for verifying incoming webhook requests. (empty for not verifying)
+//
+// It is set automatically when the webhook is set with OptionsSetWebhook.SetSecretToken(),
+// so call this function only when the webhook was set with it elsewhere. (eg. before the process was restarted)
+func (b *Bot) SetWebhookSecretToken(secretToken string) {
+	b.webhookSecretToken = secretToken
+}
+
+// SetWebhookAllowedNetworks sets networks (in CIDR notation) which are allowed to send webhook requests.
+// (eg. TelegramWebhookNetworks, or nil for allowing all, which is the default)
+//
+// When the bot is running behind reverse proxies, their networks should be given as trustedProxies,
+// then the client address will be taken from their `X-Forwarded-For` or `X-Real-IP` headers.
+func (b *Bot) SetWebhookAllowedNetworks(allowedNetworks, trustedProxies []string) (err error) {
+	var allowed, trusted []*net.IPNet
+	if allowed, err = parseCIDRs(allowedNetworks); err != nil {
+		return err
+	}
+	if trusted, err = parseCIDRs(trustedProxies); err != nil {
+		return err
+	}
+
+	b.webhookAllowedNetworks = allowed
+	b.webhookTrustedProxies = trusted
+
+	return nil
+}
+
+// Check if given webhook request is from allowed networks, and has a valid secret token.
+func (b *Bot) isAllowedWebhookRequest(req *http.Request) bool {
+	if len(b.webhookAllowedNetworks) > 0 {
+		ip := b.webhookClientIP(req)
+		if ip == nil || !containsIP(b.webhookAllowedNetworks, ip) {
+			b.error("webhook request from a disallowed address: %s", req.RemoteAddr)
+
+			return false
+		}
+	}
+
+	if b.webhookSecretToken != "" {
+		token := req.Header.Get(webhookSecretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(b.webhookSecretToken)) != 1 {
+			b.error("webhook request with an invalid secret token from: %s", req.RemoteAddr)
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// Get the client ip address of given webhook request.
+//
+// Forwarded headers are used only when the request came through trusted proxies.
+func (b *Bot) webhookClientIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !containsIP(b.webhookTrustedProxies, ip) {
+		return ip
+	}
+
+	// take the rightmost address which is not a trusted proxy
+	if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
+		addrs := strings.Split(forwarded, ",")
+		for i := len(addrs) - 1; i >= 0; i-- {
+			forwardedIP := net.ParseIP(strings.TrimSpace(addrs[i]))
+			if forwardedIP == nil {
+				return nil
+			}
+			if !containsIP(b.webhookTrustedProxies, forwardedIP) {
+				return forwardedIP
+			}
+		}
+	}
+
+	if realIP := req.Header.Get("X-Real-IP"); realIP != "" {
+		return net.ParseIP(strings.TrimSpace(realIP))
+	}
+
+	return ip
+}
+
+// Parse given CIDR strings.
+func parseCIDRs(cidrs []string) (networks []*net.IPNet, err error) {
+	for _, cidr := range cidrs {
+		var network *net.IPNet
+		if _, network, err = net.ParseCIDR(cidr); err != nil {
+			return nil, fmt.Errorf("invalid network '%s': %s", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// Check if given ip address is in one of given networks.
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}